@@ -0,0 +1,99 @@
+// Package audit looks through the vault for passwords worth changing.
+package audit
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/strength"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+// Weak is an entry whose password scores below uconst.AuditMinScore.
+type Weak struct {
+	ID       string
+	Strength strength.Result
+}
+
+// Stale is an entry whose password is older than uconst.AuditMaxAge. A zero
+// Changed means it was last changed before changes were tracked.
+type Stale struct {
+	ID      string
+	Changed time.Time
+}
+
+// Breached is an entry whose password the last breach check found, Count
+// times.
+type Breached struct {
+	ID    string
+	Count int64
+}
+
+// Report lists the findings, each worst first.
+type Report struct {
+	Breached []Breached
+	Weak     []Weak
+	// Reused holds groups of entries sharing a password
+	Reused [][]string
+	Stale  []Stale
+}
+
+// Empty is whether nothing was found.
+func (r Report) Empty() bool {
+	return len(r.Breached) == 0 && len(r.Weak) == 0 && len(r.Reused) == 0 && len(r.Stale) == 0
+}
+
+// Run audits every entry with a password, taking breaches from the last
+// breach check of each.
+func Run(creds map[string]state.CredInfo, now time.Time) Report {
+	var report Report
+	byPassword := make(map[string][]string)
+	for _, id := range state.SortedIDs(creds) {
+		ci := creds[id]
+		if ci.Password == "" {
+			continue
+		}
+		byPassword[ci.Password] = append(byPassword[ci.Password], id)
+
+		if ci.Breach.Count > 0 {
+			report.Breached = append(report.Breached, Breached{ID: id, Count: ci.Breach.Count})
+		}
+		if result := strength.Check(ci.Password, ci.Source, ci.Username); result.Score < uconst.AuditMinScore {
+			report.Weak = append(report.Weak, Weak{ID: id, Strength: result})
+		}
+		// other edits leave the password as old as it was
+		if changed := ci.PasswordChanged(); uconst.AuditMaxAge > 0 && now.Sub(changed) > uconst.AuditMaxAge {
+			report.Stale = append(report.Stale, Stale{ID: id, Changed: changed})
+		}
+	}
+
+	for _, ids := range byPassword {
+		if len(ids) > 1 {
+			report.Reused = append(report.Reused, ids)
+		}
+	}
+
+	slices.SortStableFunc(report.Breached, func(a, b Breached) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	slices.SortStableFunc(report.Weak, func(a, b Weak) int {
+		return cmp.Compare(a.Strength.Guesses, b.Strength.Guesses)
+	})
+	// largest groups first, then in the order of their first entry
+	order := make(map[string]int)
+	for i, id := range state.SortedIDs(creds) {
+		order[id] = i
+	}
+	slices.SortFunc(report.Reused, func(a, b []string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return order[a[0]] - order[b[0]]
+	})
+	slices.SortStableFunc(report.Stale, func(a, b Stale) int {
+		return a.Changed.Compare(b.Changed)
+	})
+	return report
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+func TestRun(t *testing.T) {
+	uconst.AuditMinScore = 3
+	uconst.AuditMaxAge = 365 * 24 * time.Hour
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	strong := "x7#Kq9!vLm2$Wp4z"
+
+	creds := map[string]state.CredInfo{
+		"mail":    {Source: "Mail", Password: "password", Created: now},
+		"bank":    {Source: "Bank", Password: "password", Created: now},
+		"forum":   {Source: "Forum", Password: "forum2024", Created: now},
+		"old":     {Source: "Old", Password: strong + "1", Created: now.AddDate(-2, 0, 0), Modified: now},
+		"ancient": {Source: "Ancient", Password: strong + "2"},
+		"shop":    {Source: "Shop", Password: strong + "3", Created: now, Breach: state.Breach{Checked: now, Count: 3}},
+		"work":    {Source: "Work", Password: strong + "4", Created: now, Breach: state.Breach{Checked: now, Count: 40}},
+		"empty":   {Source: "Empty", Created: now.AddDate(-2, 0, 0)},
+		"recent": {Source: "Recent", Password: strong + "5", Created: now.AddDate(-3, 0, 0),
+			History: []state.PreviousPassword{
+				{Password: "older", Replaced: now.AddDate(-2, 0, 0)},
+				{Password: "before", Replaced: now.AddDate(0, -6, 0)},
+			}},
+		"rotated": {Source: "Rotated", Password: strong + "6", Created: now.AddDate(-3, 0, 0), Modified: now,
+			History: []state.PreviousPassword{{Password: "before", Replaced: now.AddDate(0, -18, 0)}}},
+		"shared1": {Source: "Shared", Password: strong, Created: now},
+		"shared2": {Source: "Shared", Password: strong, Created: now},
+		"shared3": {Source: "Also Shared", Password: strong, Created: now},
+	}
+	report := Run(creds, now)
+
+	var ids []string
+	for _, breached := range report.Breached {
+		ids = append(ids, breached.ID)
+	}
+	if want := []string{"work", "shop"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("breached: got %v, want %v", ids, want)
+	}
+
+	ids = nil
+	for _, weak := range report.Weak {
+		ids = append(ids, weak.ID)
+	}
+	// equally weak entries stay in the order of their source
+	if want := []string{"bank", "mail", "forum"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("weak: got %v, want %v", ids, want)
+	}
+
+	if want := [][]string{{"shared3", "shared1", "shared2"}, {"bank", "mail"}}; !reflect.DeepEqual(report.Reused, want) {
+		t.Errorf("reused: got %v, want %v", report.Reused, want)
+	}
+
+	ids = nil
+	for _, stale := range report.Stale {
+		ids = append(ids, stale.ID)
+	}
+	// never tracked counts as oldest, and only changing the password counts
+	if want := []string{"ancient", "old", "rotated"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("stale: got %v, want %v", ids, want)
+	}
+	if !report.Stale[0].Changed.IsZero() {
+		t.Errorf("stale: got %v for an untracked change", report.Stale[0].Changed)
+	}
+
+	uconst.AuditMaxAge = 0
+	if report := Run(creds, now); len(report.Stale) != 0 {
+		t.Errorf("stale with no max age: got %v", report.Stale)
+	}
+	if !Run(map[string]state.CredInfo{"empty": {Source: "Empty"}}, now).Empty() {
+		t.Errorf("an entry without a password should not be reported")
+	}
+}
@@ -0,0 +1,92 @@
+package auditscreen
+
+import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/dismint/dispass/internal/audit"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+type KeyMap struct {
+	Quit key.Binding
+	Nav  key.Binding
+	Open key.Binding
+	Back key.Binding
+}
+
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Quit, k.Nav, k.Open, k.Back}
+}
+
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Quit, k.Nav},
+		{k.Open, k.Back},
+	}
+}
+
+var keyMap = KeyMap{
+	Quit: key.NewBinding(
+		key.WithKeys("ctrl+c"),
+		key.WithHelp("ctrl+c", "quit"),
+	),
+	Nav: key.NewBinding(
+		key.WithKeys("up", "down", "k", "j"),
+		key.WithHelp("↑↓", "nav"),
+	),
+	Open: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("↵", "open"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+type section int
+
+const (
+	sectionBreached section = iota
+	sectionWeak
+	sectionReused
+	sectionStale
+)
+
+// finding is a row of the report, an entry can show up in every section
+type finding struct {
+	section section
+	id      string
+	// index into the report's list for the section
+	index int
+}
+
+// visibleRows is how many lines of the report show at once
+const visibleRows = 14
+
+type Model struct {
+	keyMap    KeyMap
+	helpModel help.Model
+
+	report   audit.Report
+	findings []finding
+	loc      int
+	// offset is the first line of the report on screen
+	offset int
+}
+
+func Initial() Model {
+	helpModel := help.New()
+	helpModel.Styles = uconst.HelpStyles
+	helpModel.ShowAll = true
+
+	return Model{
+		keyMap:    keyMap,
+		helpModel: helpModel,
+
+		// report
+		findings: make([]finding, 0),
+		// loc
+		// offset
+	}
+}
@@ -0,0 +1,76 @@
+package auditscreen
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dismint/dispass/internal/audit"
+	"github.com/dismint/dispass/internal/state"
+)
+
+// populate runs the audit again, as entries may have changed since.
+func (m *Model) populate(sm *state.Model) {
+	m.report = audit.Run(sm.KeyToCredInfo, time.Now())
+	m.findings = m.findings[:0]
+	for i, breached := range m.report.Breached {
+		m.findings = append(m.findings, finding{section: sectionBreached, id: breached.ID, index: i})
+	}
+	for i, weak := range m.report.Weak {
+		m.findings = append(m.findings, finding{section: sectionWeak, id: weak.ID, index: i})
+	}
+	for i, group := range m.report.Reused {
+		for _, id := range group {
+			m.findings = append(m.findings, finding{section: sectionReused, id: id, index: i})
+		}
+	}
+	for i, stale := range m.report.Stale {
+		m.findings = append(m.findings, finding{section: sectionStale, id: stale.ID, index: i})
+	}
+	m.loc = 0
+	m.offset = 0
+}
+
+func (m *Model) transitionState(sm *state.Model) {
+	sm.Screen = state.InteractScreen
+	sm.Dirty = true
+}
+
+// open goes to the selected entry on the interact screen.
+func (m *Model) open(sm *state.Model) tea.Cmd {
+	id := m.findings[m.loc].id
+	m.transitionState(sm)
+	return func() tea.Msg { return state.SelectEntryMsg{ID: id} }
+}
+
+func (m *Model) Update(msg tea.Msg, sm *state.Model) tea.Cmd {
+	cmds := make([]tea.Cmd, 0)
+
+	if sm.Dirty {
+		m.populate(sm)
+		return nil
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keyMap.Quit):
+			sm.Quitting = true
+			cmds = append(cmds, tea.Quit)
+		case key.Matches(msg, keyMap.Back):
+			m.transitionState(sm)
+		case len(m.findings) == 0:
+		case key.Matches(msg, keyMap.Open):
+			cmds = append(cmds, m.open(sm))
+		case key.Matches(msg, keyMap.Nav):
+			switch msg.String() {
+			case "up", "k":
+				m.loc = max(m.loc-1, 0)
+			case "down", "j":
+				m.loc = min(m.loc+1, len(m.findings)-1)
+			}
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
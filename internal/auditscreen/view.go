@@ -0,0 +1,120 @@
+package auditscreen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+const dateLayout = "2006-01-02"
+
+func times(count int64) string {
+	if count == 1 {
+		return "once"
+	}
+	return fmt.Sprintf("%d times", count)
+}
+
+func (m *Model) detail(f finding, sm *state.Model) string {
+	switch f.section {
+	case sectionBreached:
+		return "seen " + times(m.report.Breached[f.index].Count)
+	case sectionWeak:
+		return m.report.Weak[f.index].Strength.CrackTimeDisplay()
+	case sectionReused:
+		return sm.KeyToCredInfo[f.id].Username
+	}
+	if changed := m.report.Stale[f.index].Changed; !changed.IsZero() {
+		return changed.Local().Format(dateLayout)
+	}
+	return "unknown"
+}
+
+// explain says why the selected entry was reported.
+func (m *Model) explain(f finding, sm *state.Model) string {
+	switch f.section {
+	case sectionBreached:
+		return fmt.Sprintf("The breach check of %v found it %v, change it everywhere it is used",
+			sm.KeyToCredInfo[f.id].Breach.Checked.Local().Format(dateLayout),
+			times(m.report.Breached[f.index].Count),
+		)
+	case sectionWeak:
+		result := m.report.Weak[f.index].Strength
+		explanation := fmt.Sprintf("Rated %v, cracked in %v", result.Label(), result.CrackTimeDisplay())
+		if warning := result.Warning(); warning != "" {
+			explanation += ". " + warning
+		}
+		return explanation
+	case sectionReused:
+		others := make([]string, 0)
+		for _, id := range m.report.Reused[f.index] {
+			if id != f.id {
+				others = append(others, sm.KeyToCredInfo[id].Source)
+			}
+		}
+		return "Same password as " + strings.Join(others, ", ")
+	}
+	if changed := m.report.Stale[f.index].Changed; !changed.IsZero() {
+		return "Unchanged since " + changed.Local().Format(dateLayout)
+	}
+	return "Last changed before changes were tracked"
+}
+
+func (m *Model) View(sm *state.Model) string {
+	if len(m.findings) == 0 {
+		return uconst.ViewStyle.Render(fmt.Sprintf("%v\n\n%v\n",
+			m.helpModel.View(m.keyMap),
+			uconst.TextStyle.Render("No breached, weak, reused or stale passwords"),
+		))
+	}
+
+	reused := len(m.findings) - len(m.report.Breached) - len(m.report.Weak) - len(m.report.Stale)
+	headers := map[section]string{
+		sectionBreached: fmt.Sprintf("Breached (%d)", len(m.report.Breached)),
+		sectionWeak:     fmt.Sprintf("Weak (%d)", len(m.report.Weak)),
+		sectionReused:   fmt.Sprintf("Reused (%d)", reused),
+		sectionStale:    fmt.Sprintf("Stale (%d)", len(m.report.Stale)),
+	}
+	lines := make([]string, 0)
+	cursorLine := 0
+	for loc, f := range m.findings {
+		switch {
+		case loc == 0 || f.section != m.findings[loc-1].section:
+			if loc > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, uconst.SymbolStyle.Render(headers[f.section]))
+		case f.section == sectionReused && f.index != m.findings[loc-1].index:
+			// keep groups apart
+			lines = append(lines, "")
+		}
+
+		prefix := " "
+		if loc == m.loc {
+			prefix = uconst.SymbolStyle.Render(">")
+			cursorLine = len(lines)
+		}
+		lines = append(lines, fmt.Sprintf("%v %v %v",
+			prefix,
+			uconst.TruncAndPadListElem(sm.KeyToCredInfo[f.id].Source),
+			uconst.TextStyle.Render(m.detail(f, sm)),
+		))
+	}
+
+	// scroll just enough to keep the cursor, and the header above the first
+	// row, on screen
+	if cursorLine < m.offset || m.loc == 0 {
+		m.offset = max(cursorLine-1, 0)
+	} else if cursorLine >= m.offset+visibleRows {
+		m.offset = cursorLine - visibleRows + 1
+	}
+	end := min(m.offset+visibleRows, len(lines))
+
+	return uconst.ViewStyle.Render(fmt.Sprintf("%v\n\n%v\n\n%v\n",
+		m.helpModel.View(m.keyMap),
+		strings.Join(lines[m.offset:end], "\n"),
+		uconst.TextStyle.Render(m.explain(m.findings[m.loc], sm)),
+	))
+}
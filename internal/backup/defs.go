@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+type KeyMap struct {
+	Quit  key.Binding
+	Nav   key.Binding
+	Enter key.Binding
+	Back  key.Binding
+}
+
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Quit, k.Nav, k.Enter, k.Back}
+}
+
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Quit, k.Nav},
+		{k.Enter, k.Back},
+	}
+}
+
+var keyMap = KeyMap{
+	Quit: key.NewBinding(
+		key.WithKeys("ctrl+c"),
+		key.WithHelp("ctrl+c", "quit"),
+	),
+	Nav: key.NewBinding(
+		key.WithKeys("up", "down", "k", "j"),
+		key.WithHelp("↑↓", "nav"),
+	),
+	Enter: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("↵", "restore"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+type Model struct {
+	keyMap    KeyMap
+	helpModel help.Model
+
+	confirming bool
+
+	backups       []passio.Backup
+	backupLoc     int
+	passwordInput textinput.Model
+}
+
+func Initial() Model {
+	passwordInput := textinput.New()
+	passwordInput.CharLimit = -1
+	passwordInput.EchoMode = textinput.EchoPassword
+	passwordInput.EchoCharacter = uconst.PasswordChar
+	passwordInput.Prompt = "Backup Master » "
+	passwordInput.Cursor.Style = uconst.SymbolStyle
+	passwordInput.PromptStyle = uconst.SymbolStyle
+	passwordInput.TextStyle = uconst.TextStyle
+
+	helpModel := help.New()
+	helpModel.Styles = uconst.HelpStyles
+
+	return Model{
+		keyMap:    keyMap,
+		helpModel: helpModel,
+
+		confirming: false,
+
+		backups: make([]passio.Backup, 0),
+		// backupLoc
+		passwordInput: passwordInput,
+	}
+}
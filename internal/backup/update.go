@@ -0,0 +1,101 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+	"github.com/dismint/dispass/internal/fuzzy"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/state"
+)
+
+func (m *Model) reset() {
+	m.confirming = false
+	m.passwordInput.SetValue("")
+	m.passwordInput.Blur()
+}
+
+func (m *Model) transitionState(sm *state.Model) {
+	sm.Screen = state.InteractScreen
+	m.reset()
+}
+
+func (m *Model) restore(sm *state.Model) tea.Cmd {
+	backup := m.backups[m.backupLoc]
+	if err := passio.RestoreBackup(sm, backup, m.passwordInput.Value()); err != nil {
+		m.passwordInput.SetValue("")
+		message := "Incorrect Password"
+		if !errors.Is(err, passio.ErrIncorrectPassword) {
+			message = fmt.Sprintf("Could not restore backup: %v", err)
+		}
+		return state.NotificationMsg(message, state.MessageLevelError)
+	}
+
+	fuzzy.InitFuzzy(sm)
+	m.transitionState(sm)
+
+	return tea.Batch(
+		state.NotificationMsg(
+			fmt.Sprintf("Restored backup from %v", backup.Time.Local().Format(timeLayout)),
+			state.MessageLevelSuccess,
+		),
+		func() tea.Msg { return state.CredsReloadedMsg{} },
+	)
+}
+
+func (m *Model) Update(msg tea.Msg, sm *state.Model) tea.Cmd {
+	cmds := make([]tea.Cmd, 0)
+
+	if sm.Dirty {
+		backups, err := passio.ListBackups()
+		if err != nil {
+			log.Errorf("failed to list backups: %v", err)
+			cmds = append(cmds, state.NotificationMsg(
+				"Could not list backups",
+				state.MessageLevelError,
+			))
+		}
+		m.backups = backups
+		m.backupLoc = 0
+		m.reset()
+		return tea.Batch(cmds...)
+	}
+
+	var cmd tea.Cmd
+	m.passwordInput, cmd = m.passwordInput.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keyMap.Quit):
+			sm.Quitting = true
+			cmds = append(cmds, tea.Quit)
+		case key.Matches(msg, keyMap.Back):
+			if m.confirming {
+				m.reset()
+			} else {
+				m.transitionState(sm)
+			}
+		case key.Matches(msg, keyMap.Enter):
+			if m.confirming {
+				cmds = append(cmds, m.restore(sm))
+			} else if len(m.backups) > 0 {
+				m.confirming = true
+				cmds = append(cmds, m.passwordInput.Focus())
+			}
+		case !m.confirming && key.Matches(msg, keyMap.Nav):
+			switch msg.String() {
+			case "up", "k":
+				m.backupLoc = max(m.backupLoc-1, 0)
+			case "down", "j":
+				m.backupLoc = min(m.backupLoc+1, len(m.backups)-1)
+			}
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
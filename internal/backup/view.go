@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"fmt"
+
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+func (m *Model) View() string {
+	var backupList string
+	for loc, backup := range m.backups {
+		prefix := " "
+		if loc == m.backupLoc {
+			prefix = uconst.SymbolStyle.Render(">")
+		}
+		backupList += fmt.Sprintf("%v %v\n",
+			prefix,
+			uconst.TextStyle.Render(backup.Time.Local().Format(timeLayout)),
+		)
+	}
+	if backupList == "" {
+		backupList = "No Backups Found\n"
+	}
+
+	view := fmt.Sprintf("%v\n\n%v",
+		m.helpModel.View(m.keyMap),
+		backupList,
+	)
+	if m.confirming {
+		view += fmt.Sprintf("\n%v\n", m.passwordInput.View())
+	}
+	return uconst.ViewStyle.Render(view)
+}
@@ -0,0 +1,110 @@
+// Package breach looks passwords up in a local copy of the Have I Been Pwned
+// password hashes, so nothing ever leaves the machine.
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+type Hash string
+
+const (
+	HashSHA1 Hash = "sha1"
+	HashNTLM Hash = "ntlm"
+)
+
+// Size is the length of a hash in bytes.
+func (h Hash) Size() int {
+	if h == HashNTLM {
+		return md4.Size
+	}
+	return sha1.Size
+}
+
+// Sum hashes password the way the corpus does, NTLM being md4 over utf-16.
+func (h Hash) Sum(password string) []byte {
+	if h == HashNTLM {
+		digest := md4.New()
+		for _, unit := range utf16.Encode([]rune(password)) {
+			digest.Write([]byte{byte(unit), byte(unit >> 8)})
+		}
+		return digest.Sum(nil)
+	}
+	sum := sha1.Sum([]byte(password))
+	return sum[:]
+}
+
+func hashOfHexLength(length int) (Hash, bool) {
+	switch length {
+	case 2 * sha1.Size:
+		return HashSHA1, true
+	case 2 * md4.Size:
+		return HashNTLM, true
+	}
+	return "", false
+}
+
+// Corpus counts how often a hash was seen in breaches.
+type Corpus interface {
+	Hash() Hash
+	Count(sum []byte) (int64, error)
+	Close() error
+}
+
+// Check looks password up in the corpus.
+func Check(corpus Corpus, password string) (int64, error) {
+	return corpus.Count(corpus.Hash().Sum(password))
+}
+
+var textLine = regexp.MustCompile(`^[0-9A-Fa-f]+:\d+\r?\n`)
+
+// Open reads any of the layouts the corpus comes in: a directory of range
+// files as written by the downloader, a single sorted text file of
+// HASH:COUNT lines, or a sorted binary of records each holding the raw hash
+// and its count as a big endian uint32. The hash of text corpora is told by
+// its length, a binary one is taken to hold hash.
+func Open(path string, hash Hash) (Corpus, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return openRanges(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	head := make([]byte, 64)
+	n, _ := f.ReadAt(head, 0)
+	head = head[:n]
+	if textLine.Match(head) {
+		hash, ok := hashOfHexLength(bytes.IndexByte(head, ':'))
+		if !ok {
+			f.Close()
+			return nil, fmt.Errorf("%v: lines hold neither sha1 nor ntlm hashes", path)
+		}
+		return &textCorpus{f: f, size: info.Size(), hash: hash}, nil
+	}
+
+	recordSize := int64(hash.Size() + 4)
+	if info.Size()%recordSize != 0 {
+		f.Close()
+		return nil, fmt.Errorf("%v: neither HASH:COUNT lines nor a whole number of %v records", path, hash)
+	}
+	return &binaryCorpus{f: f, records: info.Size() / recordSize, hash: hash}, nil
+}
+
+func upperHex(sum []byte) string {
+	return strings.ToUpper(hex.EncodeToString(sum))
+}
@@ -0,0 +1,109 @@
+package breach
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSum(t *testing.T) {
+	if got := upperHex(HashSHA1.Sum("password")); got != "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+		t.Errorf("sha1: got %v", got)
+	}
+	if got := upperHex(HashNTLM.Sum("password")); got != "8846F7EAEE8FB117AD06BDD830B7586C" {
+		t.Errorf("ntlm: got %v", got)
+	}
+}
+
+// writeCorpora writes the same corpus in every layout, with enough filler
+// for the text search to narrow down over several pages.
+func writeCorpora(t *testing.T, hash Hash, counts map[string]int64) []string {
+	for i := range 5000 {
+		counts[fmt.Sprintf("filler %d", i)] = int64(i + 1)
+	}
+	hashes := make(map[string]int64)
+	for password, count := range counts {
+		hashes[upperHex(hash.Sum(password))] = count
+	}
+	sorted := make([]string, 0, len(hashes))
+	for h := range hashes {
+		sorted = append(sorted, h)
+	}
+	sort.Strings(sorted)
+
+	dir := t.TempDir()
+	var text strings.Builder
+	var bin []byte
+	ranges := make(map[string]*strings.Builder)
+	for _, h := range sorted {
+		fmt.Fprintf(&text, "%v:%d\r\n", h, hashes[h])
+
+		raw, _ := hex.DecodeString(h)
+		bin = binary.BigEndian.AppendUint32(append(bin, raw...), uint32(hashes[h]))
+
+		if ranges[h[:5]] == nil {
+			ranges[h[:5]] = &strings.Builder{}
+		}
+		fmt.Fprintf(ranges[h[:5]], "%v:%d\n", h[5:], hashes[h])
+	}
+
+	textPath := filepath.Join(dir, "pwned.txt")
+	binPath := filepath.Join(dir, "pwned.bin")
+	rangeDir := filepath.Join(dir, "ranges")
+	if err := os.WriteFile(textPath, []byte(text.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(binPath, bin, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(rangeDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// a download always has every prefix, these are the only ones checked
+	prefixes := []string{"00000"}
+	for _, password := range []string{"password", "hunter2", "not breached"} {
+		prefixes = append(prefixes, upperHex(hash.Sum(password))[:5])
+	}
+	for _, prefix := range prefixes {
+		content := ""
+		if ranges[prefix] != nil {
+			content = ranges[prefix].String()
+		} else if prefix == "00000" {
+			content = strings.Repeat("0", 2*hash.Size()-5) + ":1\n"
+		}
+		if err := os.WriteFile(filepath.Join(rangeDir, prefix+".txt"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return []string{textPath, binPath, rangeDir}
+}
+
+func TestCorpora(t *testing.T) {
+	for _, hash := range []Hash{HashSHA1, HashNTLM} {
+		counts := map[string]int64{"password": 9545824, "hunter2": 17043}
+		for _, path := range writeCorpora(t, hash, counts) {
+			corpus, err := Open(path, hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if corpus.Hash() != hash {
+				t.Errorf("%v: read as %v, want %v", path, corpus.Hash(), hash)
+			}
+			for password, want := range map[string]int64{"password": 9545824, "hunter2": 17043, "filler 4999": 5000, "not breached": 0} {
+				if strings.HasPrefix(password, "filler") && filepath.Base(path) == "ranges" {
+					continue
+				}
+				got, err := Check(corpus, password)
+				if err != nil || got != want {
+					t.Errorf("%v %v: got %v, %v, want %v", hash, filepath.Base(path), got, err, want)
+				}
+			}
+			corpus.Close()
+		}
+	}
+}
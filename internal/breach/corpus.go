@@ -0,0 +1,187 @@
+package breach
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lines are never anywhere near this long, a window this size always holds a
+// whole one
+const lineWindow = 128
+
+// textCorpus binary searches a sorted file of HASH:COUNT lines by byte
+// offset, reading lines around each offset.
+type textCorpus struct {
+	f    *os.File
+	size int64
+	hash Hash
+}
+
+func (c *textCorpus) Hash() Hash   { return c.hash }
+func (c *textCorpus) Close() error { return c.f.Close() }
+
+// lineAt finds the first line starting at or after off, returning where it
+// starts and its hash, or the size of the file if there is none.
+func (c *textCorpus) lineAt(off int64) (int64, string, error) {
+	start := off
+	if off > 0 {
+		window := make([]byte, lineWindow)
+		n, err := c.f.ReadAt(window, off-1)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, "", err
+		}
+		i := bytes.IndexByte(window[:n], '\n')
+		if i < 0 {
+			if off-1+int64(n) >= c.size {
+				return c.size, "", nil
+			}
+			return 0, "", fmt.Errorf("no line break near offset %d", off)
+		}
+		start = off + int64(i)
+	}
+	if start >= c.size {
+		return c.size, "", nil
+	}
+
+	window := make([]byte, lineWindow)
+	n, err := c.f.ReadAt(window, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, "", err
+	}
+	hash, _, _ := strings.Cut(string(window[:n]), ":")
+	return start, strings.ToUpper(hash), nil
+}
+
+func (c *textCorpus) Count(sum []byte) (int64, error) {
+	target := upperHex(sum)
+
+	// narrow down to a few pages, the first line at or after lo still sorts
+	// before target
+	lo, hi := int64(0), c.size
+	for hi-lo > 4096 {
+		mid := lo + (hi-lo)/2
+		start, hash, err := c.lineAt(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= c.size || hash >= target {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	start, _, err := c.lineAt(lo)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(io.NewSectionReader(c.f, start, c.size-start))
+	for scanner.Scan() {
+		hash, count, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		switch hash = strings.ToUpper(hash); {
+		case hash == target:
+			return strconv.ParseInt(count, 10, 64)
+		case hash > target:
+			return 0, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// binaryCorpus binary searches fixed size records of the raw hash and its
+// count.
+type binaryCorpus struct {
+	f       *os.File
+	records int64
+	hash    Hash
+}
+
+func (c *binaryCorpus) Hash() Hash   { return c.hash }
+func (c *binaryCorpus) Close() error { return c.f.Close() }
+
+func (c *binaryCorpus) Count(sum []byte) (int64, error) {
+	recordSize := int64(c.hash.Size() + 4)
+	record := make([]byte, recordSize)
+	var readErr error
+	read := func(i int64) []byte {
+		if _, err := c.f.ReadAt(record, i*recordSize); err != nil && readErr == nil {
+			readErr = err
+		}
+		return record
+	}
+
+	i := int64(sort.Search(int(c.records), func(i int) bool {
+		return bytes.Compare(read(int64(i))[:c.hash.Size()], sum) >= 0
+	}))
+	if readErr != nil {
+		return 0, readErr
+	}
+	if i == c.records || !bytes.Equal(read(i)[:c.hash.Size()], sum) {
+		return 0, readErr
+	}
+	return int64(binary.BigEndian.Uint32(record[c.hash.Size():])), readErr
+}
+
+// rangeCorpus is a directory with a file for each five character prefix,
+// holding the rest of each hash as SUFFIX:COUNT lines.
+type rangeCorpus struct {
+	dir  string
+	hash Hash
+}
+
+const rangePrefixLength = 5
+
+func openRanges(dir string) (*rangeCorpus, error) {
+	c := &rangeCorpus{dir: dir}
+	dat, err := c.read(strings.Repeat("0", rangePrefixLength))
+	if err != nil {
+		return nil, err
+	}
+	suffix, _, found := strings.Cut(string(dat), ":")
+	hash, ok := hashOfHexLength(rangePrefixLength + len(suffix))
+	if !found || !ok {
+		return nil, fmt.Errorf("%v: range files hold neither sha1 nor ntlm hashes", dir)
+	}
+	c.hash = hash
+	return c, nil
+}
+
+// read opens the range file of prefix, which the downloader names with a
+// .txt extension and the api without.
+func (c *rangeCorpus) read(prefix string) ([]byte, error) {
+	dat, err := os.ReadFile(filepath.Join(c.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		dat, err = os.ReadFile(filepath.Join(c.dir, prefix))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%v: no range file for %v, is the download complete?", c.dir, prefix)
+	}
+	return dat, err
+}
+
+func (c *rangeCorpus) Hash() Hash   { return c.hash }
+func (c *rangeCorpus) Close() error { return nil }
+
+func (c *rangeCorpus) Count(sum []byte) (int64, error) {
+	target := upperHex(sum)
+	dat, err := c.read(target[:rangePrefixLength])
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(dat), "\n") {
+		suffix, count, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if strings.EqualFold(suffix, target[rangePrefixLength:]) {
+			return strconv.ParseInt(count, 10, 64)
+		}
+	}
+	return 0, nil
+}
@@ -1,8 +1,11 @@
 package changemaster
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
 	"github.com/dismint/dispass/internal/passio"
 	"github.com/dismint/dispass/internal/state"
 )
@@ -16,11 +19,23 @@ func (m *Model) transitionState(sm *state.Model) {
 	m.confirmPasswordInput.Blur()
 }
 
-func (m *Model) passwordComplete(sm *state.Model) {
-	sm.Secret = passio.SecretFromString(m.passwordInput.Value())
-	passio.WriteStateCreds(sm)
+func (m *Model) passwordComplete(sm *state.Model) tea.Cmd {
+	removed, err := passio.ChangeMaster(sm, m.passwordInput.Value())
+	if err != nil {
+		log.Fatalf("failed to change master password: %v", err)
+	}
 
 	m.transitionState(sm)
+	if removed > 0 {
+		return state.NotificationMsg(
+			fmt.Sprintf("Updated master password, removed %d backups it could not re-encrypt", removed),
+			state.MessageLevelNotif,
+		)
+	}
+	return state.NotificationMsg(
+		"Updated master password",
+		state.MessageLevelSuccess,
+	)
 }
 
 func (m *Model) Update(msg tea.Msg, sm *state.Model) tea.Cmd {
@@ -51,11 +66,7 @@ func (m *Model) Update(msg tea.Msg, sm *state.Model) tea.Cmd {
 					cmds = append(cmds, m.passwordInput.Focus())
 					m.confirming = false
 				} else {
-					cmds = append(cmds, state.NotificationMsg(
-						"Updated master password",
-						state.MessageLevelSuccess,
-					))
-					m.passwordComplete(sm)
+					cmds = append(cmds, m.passwordComplete(sm))
 				}
 			} else if m.passwordInput.Value() != "" {
 				m.confirming = true
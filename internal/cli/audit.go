@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dismint/dispass/internal/audit"
+	"github.com/dismint/dispass/internal/state"
+)
+
+type auditOutput struct {
+	Breached []breachedOutput     `json:"breached"`
+	Weak     []weakOutput         `json:"weak"`
+	Reused   [][]auditEntryOutput `json:"reused"`
+	Stale    []staleOutput        `json:"stale"`
+}
+
+type auditEntryOutput struct {
+	ID       string `json:"id"`
+	Source   string `json:"source"`
+	Username string `json:"username"`
+}
+
+type weakOutput struct {
+	auditEntryOutput
+	Score     int     `json:"score"`
+	Guesses   float64 `json:"guesses"`
+	CrackTime string  `json:"crack_time"`
+	Warning   string  `json:"warning,omitempty"`
+}
+
+// staleOutput leaves out changed for passwords changed before changes were
+// tracked.
+type staleOutput struct {
+	auditEntryOutput
+	Changed *time.Time `json:"changed,omitempty"`
+}
+
+func newAuditOutput(sm *state.Model, report audit.Report) auditOutput {
+	entry := func(id string) auditEntryOutput {
+		ci := sm.KeyToCredInfo[id]
+		return auditEntryOutput{ID: id, Source: ci.Source, Username: ci.Username}
+	}
+
+	output := auditOutput{
+		Breached: make([]breachedOutput, 0, len(report.Breached)),
+		Weak:     make([]weakOutput, 0, len(report.Weak)),
+		Reused:   make([][]auditEntryOutput, 0, len(report.Reused)),
+		Stale:    make([]staleOutput, 0, len(report.Stale)),
+	}
+	for _, breached := range report.Breached {
+		output.Breached = append(output.Breached, breachedOutput{auditEntryOutput: entry(breached.ID), Count: breached.Count})
+	}
+	for _, weak := range report.Weak {
+		output.Weak = append(output.Weak, weakOutput{
+			auditEntryOutput: entry(weak.ID),
+			Score:            weak.Strength.Score,
+			Guesses:          weak.Strength.Guesses,
+			CrackTime:        weak.Strength.CrackTimeDisplay(),
+			Warning:          weak.Strength.Warning(),
+		})
+	}
+	for _, group := range report.Reused {
+		entries := make([]auditEntryOutput, 0, len(group))
+		for _, id := range group {
+			entries = append(entries, entry(id))
+		}
+		output.Reused = append(output.Reused, entries)
+	}
+	for _, stale := range report.Stale {
+		output.Stale = append(output.Stale, staleOutput{
+			auditEntryOutput: entry(stale.ID),
+			Changed:          optionalTime(stale.Changed),
+		})
+	}
+	return output
+}
+
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	var master secretFlags
+	master.register(fs, "master", "master password")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageError("audit")
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+	report := newAuditOutput(sm, audit.Run(sm.KeyToCredInfo, time.Now()))
+	if outputFormat == formatJSON {
+		writeJSON(document{Audit: &report})
+		return nil
+	}
+
+	rows := make([][]string, 0)
+	for _, breached := range report.Breached {
+		rows = append(rows, []string{"breached", breached.ID, breached.Source, "seen " + strconv.FormatInt(breached.Count, 10) + " times"})
+	}
+	for _, weak := range report.Weak {
+		rows = append(rows, []string{"weak", weak.ID, weak.Source, "cracked in " + weak.CrackTime})
+	}
+	for i, group := range report.Reused {
+		for _, entry := range group {
+			rows = append(rows, []string{"reused", entry.ID, entry.Source, "group " + strconv.Itoa(i+1)})
+		}
+	}
+	for _, stale := range report.Stale {
+		detail := "changed before tracking"
+		if stale.Changed != nil {
+			detail = "changed " + stale.Changed.Local().Format(time.DateOnly)
+		}
+		rows = append(rows, []string{"stale", stale.ID, stale.Source, detail})
+	}
+
+	if outputFormat == formatTSV {
+		writeTSV([]string{"finding", "id", "source", "detail"}, rows)
+		return nil
+	}
+	writeTable(rows)
+	reused := len(rows) - len(report.Breached) - len(report.Weak) - len(report.Stale)
+	fmt.Fprintf(os.Stderr, "%d breached, %d weak, %d reused in %d groups, %d stale\n",
+		len(report.Breached), len(report.Weak), reused, len(report.Reused), len(report.Stale))
+	return nil
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dismint/dispass/internal/passio"
+)
+
+type backupOutput struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+}
+
+func newBackupOutput(backup passio.Backup) backupOutput {
+	return backupOutput{ID: backup.ID(), Time: backup.Time}
+}
+
+func runBackup(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			return runBackupList(args[1:])
+		case "restore":
+			return runBackupRestore(args[1:])
+		}
+	}
+	// still honour --help and --format before complaining
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	return usageError("backup")
+}
+
+func runBackupList(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageError("backup")
+	}
+
+	backups, err := passio.ListBackups()
+	if err != nil {
+		return err
+	}
+	outputs := make([]backupOutput, 0, len(backups))
+	rows := make([][]string, 0, len(backups))
+	for _, backup := range backups {
+		outputs = append(outputs, newBackupOutput(backup))
+		rows = append(rows, []string{backup.ID(), backup.Time.Local().Format(time.RFC3339)})
+	}
+
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{Backups: &outputs})
+	case formatTSV:
+		writeTSV([]string{"id", "time"}, rows)
+	default:
+		writeTable(rows)
+	}
+	return nil
+}
+
+func runBackupRestore(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	var master secretFlags
+	master.register(fs, "master", "master password")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("backup")
+	}
+
+	backup, err := resolveBackup(positional[0])
+	if err != nil {
+		return err
+	}
+	sm, password, err := unlockWithPassword(master)
+	if err != nil {
+		return err
+	}
+	if err := passio.RestoreBackup(sm, backup, password); err != nil {
+		return fmt.Errorf("could not restore backup %v: %w", backup.ID(), err)
+	}
+
+	output := newBackupOutput(backup)
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{Restored: &output})
+	case formatTSV:
+		writeTSV([]string{"restored"}, [][]string{{output.ID}})
+	default:
+		fmt.Fprintf(os.Stderr, "restored backup from %v\n", backup.Time.Local().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// resolveBackup finds the backup with id, which may be shortened to a unique
+// prefix like entry ids.
+func resolveBackup(prefix string) (passio.Backup, error) {
+	backups, err := passio.ListBackups()
+	if err != nil {
+		return passio.Backup{}, err
+	}
+	matches := make([]passio.Backup, 0)
+	for _, backup := range backups {
+		if backup.ID() == prefix {
+			return backup, nil
+		}
+		if strings.HasPrefix(backup.ID(), prefix) {
+			matches = append(matches, backup)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return passio.Backup{}, withCode(ExitNoMatch, "no backup with id %q, see dispass backup list", prefix)
+	case 1:
+		return matches[0], nil
+	}
+	return passio.Backup{}, withCode(ExitAmbiguous, "%q matches %d backups", prefix, len(matches))
+}
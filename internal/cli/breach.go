@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dismint/dispass/internal/breach"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/state"
+)
+
+type breachCheckOutput struct {
+	Checked  int              `json:"checked"`
+	Breached []breachedOutput `json:"breached"`
+}
+
+type breachedOutput struct {
+	auditEntryOutput
+	Count int64 `json:"count"`
+}
+
+func runBreachCheck(args []string) error {
+	fs := flag.NewFlagSet("breach-check", flag.ContinueOnError)
+	corpusPath := fs.String("corpus", "", "pwned passwords `file`, directory of range files or sorted binary")
+	hash := fs.String("hash", string(breach.HashSHA1), "`hash` a binary corpus holds, sha1 or ntlm, text ones are told apart by length")
+	var master secretFlags
+	master.register(fs, "master", "master password")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *corpusPath == "" {
+		return usageError("breach-check")
+	}
+	if breach.Hash(*hash) != breach.HashSHA1 && breach.Hash(*hash) != breach.HashNTLM {
+		return withCode(ExitUsage, "unknown hash %q, expected sha1 or ntlm", *hash)
+	}
+
+	corpus, err := breach.Open(*corpusPath, breach.Hash(*hash))
+	if err != nil {
+		return err
+	}
+	defer corpus.Close()
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+
+	report := breachCheckOutput{Breached: make([]breachedOutput, 0)}
+	now := time.Now()
+	for _, id := range state.SortedIDs(sm.KeyToCredInfo) {
+		ci := sm.KeyToCredInfo[id]
+		if ci.Password == "" {
+			continue
+		}
+		count, err := breach.Check(corpus, ci.Password)
+		if err != nil {
+			return err
+		}
+		ci.Breach = state.Breach{Checked: now, Count: count}
+		sm.KeyToCredInfo[id] = ci
+		report.Checked++
+		if count > 0 {
+			report.Breached = append(report.Breached, breachedOutput{
+				auditEntryOutput: auditEntryOutput{ID: id, Source: ci.Source, Username: ci.Username},
+				Count:            count,
+			})
+		}
+	}
+	passio.WriteStateCreds(sm)
+
+	if outputFormat == formatJSON {
+		writeJSON(document{BreachCheck: &report})
+		return nil
+	}
+	rows := make([][]string, 0, len(report.Breached))
+	for _, breached := range report.Breached {
+		rows = append(rows, []string{breached.ID, breached.Source, strconv.FormatInt(breached.Count, 10)})
+	}
+	if outputFormat == formatTSV {
+		writeTSV([]string{"id", "source", "count"}, rows)
+		return nil
+	}
+	writeTable(rows)
+	fmt.Fprintf(os.Stderr, "%d of %d passwords found in breaches\n", len(report.Breached), report.Checked)
+	return nil
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// exit codes are part of the interface, scripts depend on them
+const (
+	ExitOK = iota
+	ExitError
+	ExitUsage
+	ExitNoMatch
+	ExitAmbiguous
+	ExitBadPassword
+)
+
+// exitError carries the exit code a failed command should end with.
+type exitError struct {
+	code       int
+	err        error
+	candidates []entryOutput
+}
+
+func (e exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e exitError) Unwrap() error {
+	return e.err
+}
+
+func withCode(code int, format string, args ...any) error {
+	return exitError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+type command struct {
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"audit": {
+			usage:   "audit",
+			summary: "list weak, reused and stale passwords, thresholds come from dispass.toml",
+			run:     runAudit,
+		},
+		"backup": {
+			usage:   "backup list | backup restore <id>",
+			summary: "list the backups of the vault, or replace its entries with those of one",
+			run:     runBackup,
+		},
+		"breach-check": {
+			usage:   "breach-check --corpus <file or dir> [--hash sha1|ntlm]",
+			summary: "look every password up in a local copy of the pwned passwords, recording hits on the entries",
+			run:     runBreachCheck,
+		},
+		"export": {
+			usage:   "export --format dispass-encrypted|json|csv|kdbx|pass [--unsafe-plaintext] [--force] ... <file or dir>",
+			summary: "write every entry to an encrypted archive, plaintext file, keepass database or password store",
+			run:     runExport,
+		},
+		"generate": {
+			usage:   "generate [--length <n>] [--passphrase [--words <n>]] [--symbols=false] ...",
+			summary: "print a new random password, defaults come from dispass.toml",
+			run:     runGenerate,
+		},
+		"get": {
+			usage:   "get <query> [--field <field>]",
+			summary: "print a field of the single entry matching query",
+			run:     runGet,
+		},
+		"history": {
+			usage:   "history <id>",
+			summary: "print the previous passwords of an entry, newest first",
+			run:     runHistory,
+		},
+		"import": {
+			usage:   "import --from <format> [--dry-run] [--keep-duplicates] [--kdbx-password-stdin | --passphrase-stdin ...] <file or dir>",
+			summary: "add the entries of another password manager's export",
+			run:     runImport,
+		},
+		"list": {
+			usage:   "list [query]",
+			summary: "list entries, optionally only those matching query",
+			run:     runList,
+		},
+		"add": {
+			usage:   "add --source <source> [--username <username>] [--url <url>]... [--tag <tag>]... [--notes <notes>] [--password-stdin | --password-fd <n>] [--totp-stdin | --totp-fd <n>]",
+			summary: "add an entry, prompting for its password by default",
+			run:     runAdd,
+		},
+		"edit": {
+			usage:   "edit <id> [--source <source>] [--username <username>] [--url <url>]... [--tag <tag>]... [--notes <notes>] [--password-prompt | --password-stdin | --password-fd <n>] [--totp-stdin | --totp-fd <n>]",
+			summary: "change the given fields of an entry",
+			run:     runEdit,
+		},
+		"otp": {
+			usage:   "otp <query>",
+			summary: "print the current totp code of the single entry matching query",
+			run:     runOTP,
+		},
+		"rm": {
+			usage:   "rm <id> [--purge]",
+			summary: "move an entry to the trash, or delete it or a trashed one for good",
+			run:     runRm,
+		},
+		"schema": {
+			usage:   "schema",
+			summary: "print the version of the json and tsv output schema",
+			run:     runSchema,
+		},
+	}
+}
+
+// Usage describes every subcommand, for the top level help.
+func Usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "commands:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "  dispass %v\n    \t%v\n", commands[name].usage, commands[name].summary)
+	}
+	fmt.Fprintf(w, "\ncommands that unlock the vault prompt on the terminal unless given\n")
+	fmt.Fprintf(w, "--master-stdin or --master-fd <n>. ids may be shortened to a unique prefix.\n")
+	fmt.Fprintf(w, "every command takes --format json|tsv|plain, json output carries a schema version.\n")
+	fmt.Fprintf(w, "export takes it as --report, as its --format is the format of the export.\n")
+	fmt.Fprintf(w, "\nexit codes: %d ok, %d error, %d usage, %d no match, %d ambiguous match, %d bad password\n",
+		ExitOK, ExitError, ExitUsage, ExitNoMatch, ExitAmbiguous, ExitBadPassword)
+}
+
+// Run executes the subcommand in args and returns the process exit code.
+func Run(args []string) int {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "dispass: unknown command %q\n\n", args[0])
+		Usage(os.Stderr)
+		return ExitUsage
+	}
+
+	err := cmd.run(args[1:])
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+
+	var exitErr exitError
+	if !errors.As(err, &exitErr) {
+		exitErr = exitError{code: ExitError, err: err}
+	}
+	printError(exitErr.code, err, exitErr.candidates)
+	return exitErr.code
+}
+
+// parseArgs parses flags wherever they appear among the positional arguments,
+// which the flag package alone stops at.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	registerFormat(fs)
+	fs.SetOutput(io.Discard)
+	positional := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				fmt.Printf("usage: dispass %v\n\nflags:\n", commands[fs.Name()].usage)
+				fs.SetOutput(os.Stdout)
+				fs.PrintDefaults()
+				return nil, err
+			}
+			return nil, withCode(ExitUsage, "%v", err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func usageError(name string) error {
+	return withCode(ExitUsage, "usage: dispass %v", commands[name].usage)
+}
+
+func trimNewline(s string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+}
@@ -0,0 +1,531 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dismint/dispass/internal/fuzzy"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/totp"
+	"github.com/dismint/dispass/internal/uconst"
+	"github.com/google/uuid"
+)
+
+// resolveQuery finds the single entry a search query refers to. Several hits
+// are only resolved when exactly one of them has the query as its source.
+func resolveQuery(sm *state.Model, query string) (string, error) {
+	if _, exists := sm.KeyToCredInfo[query]; exists {
+		return query, nil
+	}
+
+	ids, err := fuzzy.QueryTopIDs(sm, query, uconst.SearchFrecency)
+	if err != nil {
+		return "", withCode(ExitUsage, "invalid query: %v", err)
+	}
+	switch len(ids) {
+	case 0:
+		return "", withCode(ExitNoMatch, "no entry matches %q", query)
+	case 1:
+		return ids[0], nil
+	}
+
+	exact := make([]string, 0)
+	for _, id := range ids {
+		if strings.EqualFold(sm.KeyToCredInfo[id].Source, query) {
+			exact = append(exact, id)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+	return "", ambiguous(sm.KeyToCredInfo, query, ids)
+}
+
+// resolveID finds the entry with the given id or unique id prefix.
+func resolveID(sm *state.Model, prefix string) (string, error) {
+	return resolveIDIn(sm.KeyToCredInfo, prefix)
+}
+
+// resolveIDIn is resolveID over creds rather than the entries of the vault.
+func resolveIDIn(creds map[string]state.CredInfo, prefix string) (string, error) {
+	if _, exists := creds[prefix]; exists {
+		return prefix, nil
+	}
+
+	ids := make([]string, 0)
+	for id := range creds {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", withCode(ExitNoMatch, "no entry with id %q", prefix)
+	case 1:
+		return ids[0], nil
+	}
+	return "", ambiguous(creds, prefix, ids)
+}
+
+func ambiguous(creds map[string]state.CredInfo, query string, ids []string) error {
+	candidates := make([]entryOutput, 0, len(ids))
+	for _, id := range ids {
+		candidates = append(candidates, newEntryOutput(id, creds[id], true))
+	}
+	return exitError{
+		code:       ExitAmbiguous,
+		err:        fmt.Errorf("%q matches %d entries", query, len(ids)),
+		candidates: candidates,
+	}
+}
+
+// stringList collects a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// registerDetails adds the flags for the optional fields of an entry.
+func registerDetails(fs *flag.FlagSet, urls, tags *stringList) *string {
+	fs.Var(urls, "url", "`url` of the entry, can be repeated")
+	fs.Var(tags, "tag", "`tag` of the entry, can be repeated")
+	return fs.String("notes", "", "notes of the entry")
+}
+
+// fieldValue looks up a fixed field or a custom field by name.
+func fieldValue(ci state.CredInfo, name string) (string, bool) {
+	switch name {
+	case "password":
+		return ci.Password, true
+	case "username":
+		return ci.Username, true
+	case "source":
+		return ci.Source, true
+	case "totp":
+		return ci.TOTP, true
+	case "notes":
+		return ci.Notes, true
+	case "urls":
+		return strings.Join(ci.URLs, "\n"), true
+	case "tags":
+		return strings.Join(ci.Tags, "\n"), true
+	}
+	for _, field := range ci.Fields {
+		if field.Name == name {
+			return field.Value, true
+		}
+	}
+	return "", false
+}
+
+func runGet(args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	var master secretFlags
+	master.register(fs, "master", "master password")
+	field := fs.String("field", "password", "field to print: password, username, source, totp, notes, urls, tags or a custom field name")
+	redact := fs.Bool("redact", false, "leave the password out of json and tsv output")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("get")
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+	id, err := resolveQuery(sm, positional[0])
+	if err != nil {
+		return err
+	}
+
+	ci := sm.KeyToCredInfo[id]
+
+	// structured output describes the whole entry unless asked for a field
+	fieldSet := false
+	fs.Visit(func(f *flag.Flag) { fieldSet = fieldSet || f.Name == "field" })
+	if outputFormat != formatPlain && !fieldSet {
+		printEntry(newEntryOutput(id, ci, *redact))
+		if !*redact {
+			markUsed(sm, id)
+		}
+		return nil
+	}
+
+	value, ok := fieldValue(ci, *field)
+	if !ok {
+		return withCode(ExitUsage, "unknown field %q", *field)
+	}
+	printValue(*field, value)
+	if *field == "password" || *field == "username" {
+		markUsed(sm, id)
+	}
+	return nil
+}
+
+func runOTP(args []string) error {
+	fs := flag.NewFlagSet("otp", flag.ContinueOnError)
+	var master secretFlags
+	master.register(fs, "master", "master password")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("otp")
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+	id, err := resolveQuery(sm, positional[0])
+	if err != nil {
+		return err
+	}
+
+	ci := sm.KeyToCredInfo[id]
+	if ci.TOTP == "" {
+		return fmt.Errorf("%v has no totp secret", ci.Source)
+	}
+	otpKey, err := totp.Parse(ci.TOTP)
+	if err != nil {
+		return fmt.Errorf("invalid totp secret: %w", err)
+	}
+
+	now := time.Now()
+	code := otpKey.Code(now)
+	switch outputFormat {
+	case formatJSON:
+		expires := now.Add(otpKey.Remaining(now)).Truncate(time.Second)
+		writeJSON(document{Value: &code, Expires: &expires})
+	default:
+		printValue("otp", code)
+	}
+	markUsed(sm, id)
+	return nil
+}
+
+// markUsed records that the credentials of an entry were handed out.
+func markUsed(sm *state.Model, id string) {
+	sm.KeyToCredInfo[id] = sm.KeyToCredInfo[id].Used(time.Now())
+	passio.WriteStateUsage(sm)
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	var master secretFlags
+	master.register(fs, "master", "master password")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return usageError("list")
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+
+	query := strings.Join(positional, "")
+	ids, err := fuzzy.QueryTopIDs(sm, query, uconst.SearchFrecency)
+	if err != nil {
+		return withCode(ExitUsage, "invalid query: %v", err)
+	}
+	if len(ids) == 0 && query != "" {
+		return withCode(ExitNoMatch, "no entry matches %q", query)
+	}
+	entries := make([]entryOutput, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, newEntryOutput(id, sm.KeyToCredInfo[id], true))
+	}
+	printEntries(entries)
+	return nil
+}
+
+// readTOTP reads a totp secret or otpauth URI, checking that it works.
+func readTOTP(totpFlags secretFlags, others ...secretFlags) (string, error) {
+	for _, other := range others {
+		if err := bothStdin(other, totpFlags); err != nil {
+			return "", err
+		}
+	}
+	secret, err := totpFlags.read("")
+	if err != nil {
+		return "", err
+	}
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", nil
+	}
+	if _, err := totp.Parse(secret); err != nil {
+		return "", withCode(ExitUsage, "invalid totp secret: %v", err)
+	}
+	return secret, nil
+}
+
+// readNewSecret reads a new secret, asking twice when prompting.
+func readNewSecret(secret, master secretFlags, what string) (string, error) {
+	if err := bothStdin(master, secret); err != nil {
+		return "", err
+	}
+	if secret.given() {
+		return secret.read("")
+	}
+
+	first, err := promptSecret(what + " » ")
+	if err != nil {
+		return "", err
+	}
+	second, err := promptSecret(fmt.Sprintf("%-*v » ", len(what), "Confirm"))
+	if err != nil {
+		return "", err
+	}
+	if first != second {
+		return "", withCode(ExitUsage, "passwords do not match")
+	}
+	return first, nil
+}
+
+func runAdd(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	var master, password secretFlags
+	master.register(fs, "master", "master password")
+	password.register(fs, "password", "entry password")
+	var totpFlags secretFlags
+	totpFlags.register(fs, "totp", "totp secret or otpauth URI")
+	source := fs.String("source", "", "source of the entry, such as a site name")
+	username := fs.String("username", "", "username of the entry")
+	var urls, tags stringList
+	notes := registerDetails(fs, &urls, &tags)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *source == "" {
+		return usageError("add")
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+	entryPassword, err := readNewSecret(password, master, "Password")
+	if err != nil {
+		return err
+	}
+	var entryTOTP string
+	if totpFlags.given() {
+		if entryTOTP, err = readTOTP(totpFlags, master, password); err != nil {
+			return err
+		}
+	}
+
+	id := uuid.NewString()
+	sm.KeyToCredInfo[id] = state.NewCredInfo(state.CredInfo{
+		Source:   *source,
+		Username: *username,
+		Password: entryPassword,
+		TOTP:     entryTOTP,
+		URLs:     urls,
+		Tags:     tags,
+		Notes:    *notes,
+	}, time.Now())
+	passio.WriteStateCreds(sm)
+
+	printEntry(newEntryOutput(id, sm.KeyToCredInfo[id], true))
+	return nil
+}
+
+func runEdit(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	var master, password secretFlags
+	master.register(fs, "master", "master password")
+	password.register(fs, "password", "entry password")
+	prompt := fs.Bool("password-prompt", false, "prompt for a new entry password")
+	var totpFlags secretFlags
+	totpFlags.register(fs, "totp", "totp secret or otpauth URI, an empty line removes it")
+	source := fs.String("source", "", "new source of the entry")
+	username := fs.String("username", "", "new username of the entry")
+	var urls, tags stringList
+	notes := registerDetails(fs, &urls, &tags)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("edit")
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+	id, err := resolveID(sm, positional[0])
+	if err != nil {
+		return err
+	}
+
+	existing := sm.KeyToCredInfo[id]
+	ci := existing
+	// repeated flags replace the whole list
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "source":
+			ci.Source = *source
+		case "username":
+			ci.Username = *username
+		case "url":
+			ci.URLs = urls
+		case "tag":
+			ci.Tags = tags
+		case "notes":
+			ci.Notes = *notes
+		}
+	})
+	if password.given() || *prompt {
+		if ci.Password, err = readNewSecret(password, master, "Password"); err != nil {
+			return err
+		}
+	}
+	if totpFlags.given() {
+		if ci.TOTP, err = readTOTP(totpFlags, master, password); err != nil {
+			return err
+		}
+	}
+	ci = existing.Edited(ci, time.Now())
+	sm.KeyToCredInfo[id] = ci
+	passio.WriteStateCreds(sm)
+
+	printEntry(newEntryOutput(id, ci, true))
+	return nil
+}
+
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	var master secretFlags
+	master.register(fs, "master", "master password")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("history")
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+	id, err := resolveID(sm, positional[0])
+	if err != nil {
+		return err
+	}
+
+	history := make([]historyOutput, 0)
+	rows := make([][]string, 0)
+	for _, previous := range sm.KeyToCredInfo[id].History {
+		history = append(history, historyOutput{
+			Password: previous.Password,
+			Replaced: previous.Replaced,
+		})
+		rows = append(rows, []string{
+			previous.Replaced.Format(time.RFC3339),
+			previous.Password,
+		})
+	}
+
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{History: &history})
+	case formatTSV:
+		writeTSV([]string{"replaced", "password"}, rows)
+	default:
+		writeTable(rows)
+	}
+	return nil
+}
+
+func runRm(args []string) error {
+	fs := flag.NewFlagSet("rm", flag.ContinueOnError)
+	var master secretFlags
+	master.register(fs, "master", "master password")
+	purge := fs.Bool("purge", false, "delete for good instead of moving to the trash")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("rm")
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+	creds := sm.KeyToCredInfo
+	if *purge {
+		// entries already in the trash can be purged too
+		creds = make(map[string]state.CredInfo, len(sm.KeyToCredInfo)+len(sm.Trash))
+		maps.Copy(creds, sm.KeyToCredInfo)
+		for id, trashed := range sm.Trash {
+			creds[id] = trashed.CredInfo
+		}
+	}
+	id, err := resolveIDIn(creds, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if *purge {
+		delete(sm.KeyToCredInfo, id)
+		delete(sm.Trash, id)
+	} else {
+		sm.TrashCred(id, time.Now())
+	}
+	passio.WriteStateCreds(sm)
+
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{Deleted: id})
+	case formatTSV:
+		writeTSV([]string{"deleted"}, [][]string{{id}})
+	default:
+		fmt.Fprintf(os.Stderr, "deleted %v\n", id)
+	}
+	return nil
+}
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageError("schema")
+	}
+
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{})
+	default:
+		printValue("schema", fmt.Sprint(SchemaVersion))
+	}
+	return nil
+}
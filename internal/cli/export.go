@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dismint/dispass/internal/exporter"
+	"github.com/dismint/dispass/internal/gpg"
+	"github.com/dismint/dispass/internal/kdbx"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/perm"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+type exportOutput struct {
+	Format  string `json:"format"`
+	Path    string `json:"path"`
+	Entries int    `json:"entries"`
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	var master, kdbxPassword, passphrase secretFlags
+	master.register(fs, "master", "master password")
+	kdbxPassword.register(fs, "kdbx-password", "password of the kdbx database")
+	passphrase.register(fs, "passphrase", "passphrase of a dispass-encrypted archive")
+	// --format names what is written here, the report takes --report instead
+	var to string
+	fs.StringVar(&to, "format", "", "`format` to export to: dispass-encrypted, json, csv, kdbx or pass")
+	fs.StringVar(&to, "to", "", "same as --format")
+	fs.Var(&outputFormat, "report", "report `format`: json, tsv or plain")
+	kdfName := fs.String("kdf", "argon2id", "kdbx key derivation: argon2id, argon2d or aes")
+	cipherName := fs.String("cipher", "aes", "kdbx cipher: aes or chacha20")
+	var keys stringList
+	fs.Var(&keys, "key", "gpg `key` to encrypt a pass store for, can be repeated")
+	force := fs.Bool("force", false, "overwrite files that exist")
+	unsafePlaintext := fs.Bool("unsafe-plaintext", false, "allow json and csv exports, which are not encrypted")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return usageError("export")
+	}
+	path := positional[0]
+
+	var export func(sm *state.Model) error
+	switch to {
+	case "dispass-encrypted":
+		export, err = archiveExport(path, *force, master, passphrase)
+	case "json", "csv":
+		export, err = plaintextExport(path, to, *unsafePlaintext, *force)
+	case "kdbx":
+		export, err = kdbxExport(path, *kdfName, *cipherName, *force, master, kdbxPassword)
+	case "pass":
+		export, err = passExport(path, keys, *force)
+	default:
+		return usageError("export")
+	}
+	if err != nil {
+		return err
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+	if err := export(sm); err != nil {
+		return err
+	}
+
+	report := exportOutput{Format: to, Path: path, Entries: len(sm.KeyToCredInfo)}
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{Export: &report})
+	case formatTSV:
+		writeTSV([]string{"format", "path", "entries"}, [][]string{{report.Format, report.Path, fmt.Sprint(report.Entries)}})
+	default:
+		fmt.Fprintf(os.Stderr, "exported %d entries to %v\n", report.Entries, path)
+	}
+	return nil
+}
+
+func checkOverwrite(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%v already exists, use --force to overwrite it", path)
+	}
+	return nil
+}
+
+// writePrivate writes a file only its owner can read. An existing file is
+// only replaced with force, and then by renaming a new file over it, so its
+// permissions don't carry over and a failed export leaves it as it was.
+func writePrivate(path string, data []byte, force bool) error {
+	if force {
+		return passio.WriteFileAtomic(path, data, perm.FileMode)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm.FileMode)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+// archiveExport writes everything to an archive that only needs its own
+// passphrase to open, see passio.EncodeArchive.
+func archiveExport(path string, force bool, master, passphrase secretFlags) (func(sm *state.Model) error, error) {
+	if err := checkOverwrite(path, force); err != nil {
+		return nil, err
+	}
+
+	return func(sm *state.Model) error {
+		secret, err := readNewSecret(passphrase, master, "Export passphrase")
+		if err != nil {
+			return err
+		}
+		if secret == "" {
+			return withCode(ExitUsage, "the export passphrase can't be empty")
+		}
+		dat, err := passio.EncodeArchive(sm.KeyToCredInfo, secret)
+		if err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		return writePrivate(path, dat, force)
+	}, nil
+}
+
+// plaintextExport writes every secret out in the clear, so it takes an
+// explicit flag and the master password a second time.
+func plaintextExport(path, format string, unsafePlaintext, force bool) (func(sm *state.Model) error, error) {
+	if !unsafePlaintext {
+		return nil, withCode(ExitUsage, "%v exports are not encrypted, use --unsafe-plaintext to write one anyway, or --format dispass-encrypted", format)
+	}
+	if err := checkOverwrite(path, force); err != nil {
+		return nil, err
+	}
+
+	return func(sm *state.Model) error {
+		if err := confirmMaster(sm); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if format == "json" {
+			writePlaintextJSON(&buf, sm.KeyToCredInfo)
+		} else if err := writePlaintextCSV(&buf, sm.KeyToCredInfo); err != nil {
+			return err
+		}
+		return writePrivate(path, buf.Bytes(), force)
+	}, nil
+}
+
+// confirmMaster asks for the master password again on the terminal, even
+// when it was given some other way.
+func confirmMaster(sm *state.Model) error {
+	password, err := promptSecret("Re-enter master » ")
+	if errors.As(err, &exitError{}) {
+		return withCode(ExitUsage, "plaintext exports need the master password re-entered on a terminal")
+	} else if err != nil {
+		return err
+	}
+	secret, err := sm.KDF.Derive(password)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(secret, sm.Secret) != 1 {
+		return withCode(ExitBadPassword, "incorrect password")
+	}
+	return nil
+}
+
+// kdbxExport checks the options before anything is unlocked, so mistakes
+// don't cost a password prompt.
+func kdbxExport(path, kdfName, cipherName string, force bool, master, kdbxPassword secretFlags) (func(sm *state.Model) error, error) {
+	opts := kdbx.DefaultOptions()
+	switch kdfName {
+	case "argon2id":
+	case "argon2d":
+		opts.KDF = kdbx.KDFArgon2d
+	case "aes":
+		opts.KDF = kdbx.KDFAES
+		opts.Iterations = kdbx.DefaultAESRounds
+	default:
+		return nil, withCode(ExitUsage, "unknown kdf %q, expected argon2id, argon2d or aes", kdfName)
+	}
+	switch cipherName {
+	case "aes":
+	case "chacha20":
+		opts.Cipher = kdbx.CipherChaCha20
+	default:
+		return nil, withCode(ExitUsage, "unknown cipher %q, expected aes or chacha20", cipherName)
+	}
+	if err := checkOverwrite(path, force); err != nil {
+		return nil, err
+	}
+
+	return func(sm *state.Model) error {
+		password, err := readNewSecret(kdbxPassword, master, "KeePass password")
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := exporter.KDBX(&buf, sm.KeyToCredInfo, password, opts); err != nil {
+			return fmt.Errorf("failed to write kdbx: %w", err)
+		}
+		return writePrivate(path, buf.Bytes(), force)
+	}, nil
+}
+
+// passExport encrypts for the keys given, or those of the store being
+// written into, or the configured one.
+func passExport(dir string, keys []string, force bool) (func(sm *state.Model) error, error) {
+	recipients := keys
+	if len(recipients) == 0 {
+		existing, err := exporter.ReadGPGID(dir)
+		if err != nil {
+			return nil, err
+		}
+		recipients = existing
+	}
+	if len(recipients) == 0 && uconst.GPGKey != "" {
+		recipients = []string{uconst.GPGKey}
+	}
+	if len(recipients) == 0 {
+		return nil, withCode(ExitUsage, "no gpg key to encrypt for, use --key or set pass.key in the config")
+	}
+
+	return func(sm *state.Model) error {
+		return exporter.Pass(dir, sm.KeyToCredInfo, recipients, gpg.Encrypt, force)
+	}, nil
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/dismint/dispass/internal/passgen"
+)
+
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	opts := passgen.DefaultOptions()
+	fs.BoolVar(&opts.Passphrase, "passphrase", opts.Passphrase, "generate a diceware passphrase instead of a password")
+	fs.IntVar(&opts.Length, "length", opts.Length, "number of characters")
+	fs.BoolVar(&opts.Lower, "lower", opts.Lower, "use lowercase letters")
+	fs.BoolVar(&opts.Upper, "upper", opts.Upper, "use uppercase letters")
+	fs.BoolVar(&opts.Digits, "digits", opts.Digits, "use digits")
+	fs.BoolVar(&opts.Symbols, "symbols", opts.Symbols, "use symbols")
+	fs.BoolVar(&opts.ExcludeAmbiguous, "exclude-ambiguous", opts.ExcludeAmbiguous, "leave out characters such as l, 1, O and 0")
+	fs.IntVar(&opts.MinPerClass, "min-per-class", opts.MinPerClass, "minimum characters from each enabled class")
+	fs.IntVar(&opts.Words, "words", opts.Words, "number of passphrase words")
+	fs.StringVar(&opts.Separator, "separator", opts.Separator, "passphrase word separator")
+	fs.BoolVar(&opts.Capitalize, "capitalize", opts.Capitalize, "capitalize passphrase words")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return usageError("generate")
+	}
+
+	password, err := passgen.Generate(opts)
+	if err != nil {
+		return withCode(ExitUsage, "%v", err)
+	}
+	printValue("password", password)
+	return nil
+}
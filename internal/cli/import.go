@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dismint/dispass/internal/gpg"
+	"github.com/dismint/dispass/internal/importer"
+	"github.com/dismint/dispass/internal/kdbx"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/google/uuid"
+)
+
+type importOutput struct {
+	DryRun     bool              `json:"dry_run"`
+	Imported   []importedOutput  `json:"imported"`
+	Duplicates []duplicateOutput `json:"duplicates"`
+	Skipped    []skipOutput      `json:"skipped"`
+	Partial    []partialOutput   `json:"partial"`
+}
+
+type importedOutput struct {
+	Where string `json:"where"`
+	entryOutput
+}
+
+type duplicateOutput struct {
+	Where    string `json:"where"`
+	Source   string `json:"source"`
+	Username string `json:"username"`
+	Existing string `json:"existing"`
+}
+
+type skipOutput struct {
+	Where  string `json:"where"`
+	Reason string `json:"reason"`
+}
+
+// partialOutput is an imported entry that lost some of what was exported.
+type partialOutput struct {
+	Where    string   `json:"where"`
+	ID       string   `json:"id"`
+	Unmapped []string `json:"unmapped"`
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	var master, kdbxPassword, passphrase secretFlags
+	master.register(fs, "master", "master password")
+	kdbxPassword.register(fs, "kdbx-password", "password of a kdbx database")
+	passphrase.register(fs, "passphrase", "passphrase of a dispass-encrypted archive")
+	from := fs.String("from", "", "`format` of the export: "+strings.Join(importer.Formats(), ", "))
+	dryRun := fs.Bool("dry-run", false, "only report what would be imported")
+	keepDuplicates := fs.Bool("keep-duplicates", false, "import entries that look like existing ones anyway")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *from == "" {
+		return usageError("import")
+	}
+
+	result, err := readExport(importer.Format(*from), positional[0], master, kdbxPassword, passphrase)
+	if err != nil {
+		return err
+	}
+
+	sm, err := unlock(master)
+	if err != nil {
+		return err
+	}
+
+	report := importRecords(sm, result, *keepDuplicates)
+	report.DryRun = *dryRun
+	if !*dryRun && len(report.Imported) > 0 {
+		passio.WriteStateCreds(sm)
+	}
+	printImport(report)
+	return nil
+}
+
+// readExport parses the export at path, pass stores are directories and
+// decrypted with gpg.
+func readExport(format importer.Format, path string, master, kdbxPassword, passphrase secretFlags) (importer.Result, error) {
+	if format == importer.FormatPass {
+		return importer.ParsePass(path, gpg.Decrypt)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return importer.Result{}, err
+	}
+	switch format {
+	case importer.FormatKDBX:
+		if err := bothStdin(master, kdbxPassword); err != nil {
+			return importer.Result{}, err
+		}
+		password, err := kdbxPassword.read("KeePass password » ")
+		if err != nil {
+			return importer.Result{}, err
+		}
+		result, err := importer.ParseKDBX(data, password)
+		if errors.Is(err, kdbx.ErrIncorrectPassword) {
+			return importer.Result{}, withCode(ExitBadPassword, "%v", err)
+		}
+		return result, err
+	case importer.FormatDispassEncrypted:
+		if err := bothStdin(master, passphrase); err != nil {
+			return importer.Result{}, err
+		}
+		secret, err := passphrase.read("Export passphrase » ")
+		if err != nil {
+			return importer.Result{}, err
+		}
+		result, err := importer.ParseDispassEncrypted(data, secret)
+		if errors.Is(err, passio.ErrIncorrectPassword) {
+			return importer.Result{}, withCode(ExitBadPassword, "incorrect passphrase")
+		}
+		return result, err
+	default:
+		return importer.Parse(format, data)
+	}
+}
+
+// importRecords adds the records to the vault, checking each one for
+// duplicates against what is already there, including earlier records.
+func importRecords(sm *state.Model, result importer.Result, keepDuplicates bool) importOutput {
+	report := importOutput{
+		Imported:   make([]importedOutput, 0),
+		Duplicates: make([]duplicateOutput, 0),
+		Skipped:    make([]skipOutput, 0),
+		Partial:    make([]partialOutput, 0),
+	}
+	for _, skip := range result.Skipped {
+		report.Skipped = append(report.Skipped, skipOutput{Where: skip.Where, Reason: skip.Reason})
+	}
+
+	now := time.Now()
+	for _, record := range result.Records {
+		if existing, ok := importer.Duplicate(sm.KeyToCredInfo, record.CredInfo); ok && !keepDuplicates {
+			report.Duplicates = append(report.Duplicates, duplicateOutput{
+				Where:    record.Where,
+				Source:   record.CredInfo.Source,
+				Username: record.CredInfo.Username,
+				Existing: existing,
+			})
+			continue
+		}
+
+		ci := record.Entry(now)
+		id := uuid.NewString()
+		sm.KeyToCredInfo[id] = ci
+		report.Imported = append(report.Imported, importedOutput{
+			Where:       record.Where,
+			entryOutput: newEntryOutput(id, ci, true),
+		})
+		if len(record.Unmapped) > 0 {
+			report.Partial = append(report.Partial, partialOutput{
+				Where:    record.Where,
+				ID:       id,
+				Unmapped: record.Unmapped,
+			})
+		}
+	}
+	return report
+}
+
+func printImport(report importOutput) {
+	if outputFormat == formatJSON {
+		writeJSON(document{Import: &report})
+		return
+	}
+
+	rows := make([][]string, 0)
+	for _, imported := range report.Imported {
+		rows = append(rows, []string{"imported", imported.Where, imported.ID})
+	}
+	for _, duplicate := range report.Duplicates {
+		rows = append(rows, []string{"duplicate", duplicate.Where, "of " + duplicate.Existing})
+	}
+	for _, skip := range report.Skipped {
+		rows = append(rows, []string{"skipped", skip.Where, skip.Reason})
+	}
+	for _, partial := range report.Partial {
+		rows = append(rows, []string{"partial", partial.Where, "dropped " + strings.Join(partial.Unmapped, ", ")})
+	}
+
+	if outputFormat == formatTSV {
+		writeTSV([]string{"status", "where", "detail"}, rows)
+		return
+	}
+	writeTable(rows)
+	verb := "imported"
+	if report.DryRun {
+		verb = "would import"
+	}
+	fmt.Fprintf(os.Stderr, "%v %d, %d duplicates, %d skipped, %d partly mapped\n",
+		verb, len(report.Imported), len(report.Duplicates), len(report.Skipped), len(report.Partial))
+}
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+// SchemaVersion is bumped whenever json or tsv output changes in a way that
+// could break consumers, adding fields does not count
+const SchemaVersion = 1
+
+type format string
+
+const (
+	formatPlain format = "plain"
+	formatTSV   format = "tsv"
+	formatJSON  format = "json"
+)
+
+func (f *format) String() string {
+	return string(*f)
+}
+
+func (f *format) Set(value string) error {
+	switch format(value) {
+	case formatPlain, formatTSV, formatJSON:
+		*f = format(value)
+		return nil
+	}
+	return fmt.Errorf("unknown format %q, expected json, tsv or plain", value)
+}
+
+// outputFormat is shared by every command, there is only ever one per process
+var outputFormat = formatPlain
+
+// registerFormat leaves alone a --format the command registered itself, export
+// uses it for the format of the file it writes.
+func registerFormat(fs *flag.FlagSet) {
+	if fs.Lookup("format") != nil {
+		return
+	}
+	fs.Var(&outputFormat, "format", "output `format`: json, tsv or plain")
+}
+
+type entryOutput struct {
+	ID       string        `json:"id"`
+	Source   string        `json:"source"`
+	Username string        `json:"username"`
+	Password *string       `json:"password,omitempty"`
+	URLs     []string      `json:"urls,omitempty"`
+	Tags     []string      `json:"tags,omitempty"`
+	Notes    string        `json:"notes,omitempty"`
+	Fields   []fieldOutput `json:"fields,omitempty"`
+	Created  *time.Time    `json:"created,omitempty"`
+	Modified *time.Time    `json:"modified,omitempty"`
+	LastUsed *time.Time    `json:"last_used,omitempty"`
+	Uses     int           `json:"uses,omitempty"`
+}
+
+// fieldOutput is a custom field, hidden values are left out when redacting.
+type fieldOutput struct {
+	Name  string  `json:"name"`
+	Type  string  `json:"type"`
+	Value *string `json:"value,omitempty"`
+}
+
+func newEntryOutput(id string, ci state.CredInfo, redact bool) entryOutput {
+	entry := entryOutput{
+		ID:       id,
+		Source:   ci.Source,
+		Username: ci.Username,
+		URLs:     ci.URLs,
+		Tags:     ci.Tags,
+		Notes:    ci.Notes,
+		Created:  optionalTime(ci.Created),
+		Modified: optionalTime(ci.Modified),
+		LastUsed: optionalTime(ci.LastUsed),
+		Uses:     ci.UseCount,
+	}
+	if !redact {
+		entry.Password = &ci.Password
+	}
+	for _, field := range ci.Fields {
+		output := fieldOutput{Name: field.Name, Type: field.Type.String()}
+		if !redact || field.Type != state.FieldHidden {
+			output.Value = &field.Value
+		}
+		entry.Fields = append(entry.Fields, output)
+	}
+	return entry
+}
+
+// optionalTime leaves out timestamps from before they were tracked.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (e entryOutput) columns() []string {
+	columns := []string{e.ID, e.Source, e.Username}
+	if e.Password != nil {
+		columns = append(columns, *e.Password)
+	}
+	return columns
+}
+
+var entryHeader = []string{"id", "source", "username", "password"}
+
+type historyOutput struct {
+	Password string    `json:"password"`
+	Replaced time.Time `json:"replaced"`
+}
+
+type errorOutput struct {
+	Code       int           `json:"code"`
+	Name       string        `json:"name"`
+	Message    string        `json:"message"`
+	Candidates []entryOutput `json:"candidates,omitempty"`
+}
+
+var exitCodeNames = map[int]string{
+	ExitError:       "error",
+	ExitUsage:       "usage",
+	ExitNoMatch:     "no_match",
+	ExitAmbiguous:   "ambiguous",
+	ExitBadPassword: "bad_password",
+}
+
+// document is the top level of all json output.
+type document struct {
+	Schema      int                `json:"schema"`
+	Entry       *entryOutput       `json:"entry,omitempty"`
+	Entries     *[]entryOutput     `json:"entries,omitempty"`
+	History     *[]historyOutput   `json:"history,omitempty"`
+	Value       *string            `json:"value,omitempty"`
+	Expires     *time.Time         `json:"expires,omitempty"`
+	Deleted     string             `json:"deleted,omitempty"`
+	Import      *importOutput      `json:"import,omitempty"`
+	Export      *exportOutput      `json:"export,omitempty"`
+	Audit       *auditOutput       `json:"audit,omitempty"`
+	BreachCheck *breachCheckOutput `json:"breach_check,omitempty"`
+	Backups     *[]backupOutput    `json:"backups,omitempty"`
+	Restored    *backupOutput      `json:"restored,omitempty"`
+	Error       *errorOutput       `json:"error,omitempty"`
+}
+
+func writeJSON(doc document) {
+	doc.Schema = SchemaVersion
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(doc)
+}
+
+// tsvEscape keeps every record on one line with exactly the expected columns.
+var tsvEscape = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func writeTSV(header []string, rows [][]string) {
+	fmt.Println(strings.Join(header, "\t"))
+	for _, row := range rows {
+		escaped := make([]string, len(row))
+		for i, column := range row {
+			escaped[i] = tsvEscape.Replace(column)
+		}
+		fmt.Println(strings.Join(escaped, "\t"))
+	}
+}
+
+func writeTable(rows [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+// printEntries prints a list of entries in the chosen format.
+func printEntries(entries []entryOutput) {
+	rows := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, entry.columns())
+	}
+
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{Entries: &entries})
+	case formatTSV:
+		header := entryHeader[:3]
+		if len(entries) > 0 && entries[0].Password != nil {
+			header = entryHeader
+		}
+		writeTSV(header, rows)
+	default:
+		writeTable(rows)
+	}
+}
+
+// printEntry prints a single entry, plain output is just its id.
+func printEntry(entry entryOutput) {
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{Entry: &entry})
+	case formatTSV:
+		printEntries([]entryOutput{entry})
+	default:
+		fmt.Println(entry.ID)
+	}
+}
+
+// printValue prints a single field, named for tsv output.
+func printValue(name, value string) {
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{Value: &value})
+	case formatTSV:
+		writeTSV([]string{name}, [][]string{{value}})
+	default:
+		fmt.Println(value)
+	}
+}
+
+// printError reports a failed command, on stdout for json so consumers only
+// have a single stream to parse.
+func printError(code int, err error, candidates []entryOutput) {
+	switch outputFormat {
+	case formatJSON:
+		writeJSON(document{Error: &errorOutput{
+			Code:       code,
+			Name:       exitCodeNames[code],
+			Message:    err.Error(),
+			Candidates: candidates,
+		}})
+	default:
+		fmt.Fprintf(os.Stderr, "dispass: %v\n", err)
+		if len(candidates) > 0 {
+			rows := make([][]string, 0, len(candidates))
+			for _, candidate := range candidates {
+				rows = append(rows, candidate.columns())
+			}
+			w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+			for _, row := range rows {
+				fmt.Fprintln(w, "  "+strings.Join(row, "\t"))
+			}
+			w.Flush()
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+// plaintextEntry is everything about an entry, where get leaves out the totp
+// secret and history.
+type plaintextEntry struct {
+	entryOutput
+	TOTP    string          `json:"totp,omitempty"`
+	History []historyOutput `json:"history,omitempty"`
+}
+
+type plaintextDocument struct {
+	Schema  int              `json:"schema"`
+	Entries []plaintextEntry `json:"entries"`
+}
+
+var plaintextHeader = []string{
+	"id", "source", "username", "password", "totp", "urls", "tags", "notes", "fields", "created", "modified", "last_used",
+}
+
+func writePlaintextJSON(w io.Writer, creds map[string]state.CredInfo) {
+	doc := plaintextDocument{Schema: SchemaVersion, Entries: make([]plaintextEntry, 0, len(creds))}
+	for _, id := range state.SortedIDs(creds) {
+		ci := creds[id]
+		entry := plaintextEntry{entryOutput: newEntryOutput(id, ci, false), TOTP: ci.TOTP}
+		for _, previous := range ci.History {
+			entry.History = append(entry.History, historyOutput{Password: previous.Password, Replaced: previous.Replaced})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(doc)
+}
+
+// writePlaintextCSV puts lists one item per line within their cell, and
+// leaves out password history, which only the json export keeps.
+func writePlaintextCSV(w io.Writer, creds map[string]state.CredInfo) error {
+	cw := csv.NewWriter(w)
+	cw.Write(plaintextHeader)
+	for _, id := range state.SortedIDs(creds) {
+		ci := creds[id]
+		fields := make([]string, 0, len(ci.Fields))
+		for _, field := range ci.Fields {
+			fields = append(fields, field.Name+": "+field.Value)
+		}
+		cw.Write([]string{
+			id,
+			ci.Source,
+			ci.Username,
+			ci.Password,
+			ci.TOTP,
+			strings.Join(ci.URLs, "\n"),
+			strings.Join(ci.Tags, "\n"),
+			ci.Notes,
+			strings.Join(fields, "\n"),
+			csvTime(ci.Created),
+			csvTime(ci.Modified),
+			csvTime(ci.LastUsed),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dismint/dispass/internal/fuzzy"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+	"golang.org/x/term"
+)
+
+// secretFlags choose where a secret is read from, by default it is prompted
+// for on the terminal
+type secretFlags struct {
+	name  string
+	stdin bool
+	fd    int
+}
+
+func (f *secretFlags) register(fs *flag.FlagSet, name, what string) {
+	f.name = name
+	fs.BoolVar(&f.stdin, name+"-stdin", false, "read the "+what+" from the first line of stdin")
+	fs.IntVar(&f.fd, name+"-fd", -1, "read the "+what+" from the first line of file descriptor `n`")
+}
+
+func (f *secretFlags) given() bool {
+	return f.stdin || f.fd >= 0
+}
+
+func (f *secretFlags) read(prompt string) (string, error) {
+	switch {
+	case f.stdin:
+		return readLine(os.Stdin)
+	case f.fd >= 0:
+		file := os.NewFile(uintptr(f.fd), fmt.Sprintf("fd %d", f.fd))
+		if file == nil {
+			return "", withCode(ExitUsage, "invalid file descriptor %d", f.fd)
+		}
+		defer file.Close()
+		return readLine(file)
+	default:
+		return promptSecret(prompt)
+	}
+}
+
+// bothStdin rejects reading two secrets from stdin, only the first line is
+// ever read.
+func bothStdin(a, b secretFlags) error {
+	if a.stdin && b.stdin {
+		return withCode(ExitUsage, "only one of --%v-stdin and --%v-stdin can be used", a.name, b.name)
+	}
+	return nil
+}
+
+func readLine(file *os.File) (string, error) {
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("could not read from %v: %w", file.Name(), err)
+	}
+	return trimNewline(line), nil
+}
+
+// promptSecret reads a secret without echo, from stdin when it is a terminal
+// and otherwise from the controlling terminal, so stdin and stdout stay free
+// for pipes.
+func promptSecret(prompt string) (string, error) {
+	in, out := os.Stdin, os.Stderr
+	if !term.IsTerminal(int(in.Fd())) {
+		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			return "", withCode(ExitUsage, "no terminal to prompt on, use --master-stdin or --master-fd")
+		}
+		defer tty.Close()
+		in, out = tty, tty
+	}
+
+	fmt.Fprint(out, prompt)
+	secret, err := term.ReadPassword(int(in.Fd()))
+	fmt.Fprintln(out)
+	if err != nil {
+		return "", fmt.Errorf("could not read password: %w", err)
+	}
+	return string(secret), nil
+}
+
+// unlock opens the vault with the master password from master and indexes it
+// for searching.
+func unlock(master secretFlags) (*state.Model, error) {
+	sm, _, err := unlockWithPassword(master)
+	return sm, err
+}
+
+// unlockWithPassword is unlock for commands that need the master password
+// again afterwards.
+func unlockWithPassword(master secretFlags) (*state.Model, string, error) {
+	if _, err := os.Stat(uconst.DataFilePath); os.IsNotExist(err) {
+		return nil, "", fmt.Errorf("no vault at %v, run dispass to create one", uconst.DataFilePath)
+	}
+
+	password, err := master.read("Master » ")
+	if err != nil {
+		return nil, "", err
+	}
+
+	sm := state.Initial()
+	if err := passio.ReadStateCreds(&sm, password); err != nil {
+		if errors.Is(err, passio.ErrIncorrectPassword) {
+			return nil, "", withCode(ExitBadPassword, "incorrect password")
+		}
+		return nil, "", fmt.Errorf("could not open vault: %w", err)
+	}
+	fuzzy.InitFuzzy(&sm)
+
+	return &sm, password, nil
+}
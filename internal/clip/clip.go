@@ -0,0 +1,107 @@
+package clip
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/log"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+// HelperCommand is the hidden subcommand the clearing helper runs as, main
+// hands it straight to RunHelper before any other setup.
+const HelperCommand = "__clear-clipboard"
+
+type Sum [sha256.Size]byte
+
+// Copy puts value on the clipboard and starts a detached helper that clears it
+// after the configured timeout, so it happens even if dispass exits first.
+func Copy(value string) (Sum, error) {
+	sum := sha256.Sum256([]byte(value))
+	if err := clipboard.WriteAll(value); err != nil {
+		return sum, err
+	}
+	if uconst.ClipboardClearAfter > 0 {
+		if err := spawnHelper(sum, uconst.ClipboardClearAfter); err != nil {
+			log.Errorf("failed to start clipboard helper: %v", err)
+		}
+	}
+	return sum, nil
+}
+
+// ClearIfUnchanged empties the clipboard, unless something other than the
+// value we put there has replaced it in the meantime. It reports whether the
+// clipboard was cleared.
+func ClearIfUnchanged(sum Sum) (bool, error) {
+	current, err := clipboard.ReadAll()
+	if err != nil {
+		return false, err
+	}
+	currentSum := sha256.Sum256([]byte(current))
+	if subtle.ConstantTimeCompare(currentSum[:], sum[:]) != 1 {
+		return false, nil
+	}
+	return true, clipboard.WriteAll("")
+}
+
+func spawnHelper(sum Sum, after time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// the hash goes over a pipe rather than argv, where other users could
+	// see it
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if _, err := fmt.Fprintln(w, hex.EncodeToString(sum[:])); err != nil {
+		w.Close()
+		return err
+	}
+	w.Close()
+
+	cmd := exec.Command(exe, HelperCommand, after.String())
+	cmd.Stdin = r
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// RunHelper is the body of the helper process, it returns the exit code.
+func RunHelper(args []string) int {
+	if len(args) != 1 {
+		return 2
+	}
+	after, err := time.ParseDuration(args[0])
+	if err != nil {
+		return 2
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return 1
+	}
+	decoded, err := hex.DecodeString(strings.TrimSpace(line))
+	if err != nil || len(decoded) != sha256.Size {
+		return 2
+	}
+
+	time.Sleep(after)
+	if _, err := ClearIfUnchanged(Sum(decoded)); err != nil {
+		return 1
+	}
+	return 0
+}
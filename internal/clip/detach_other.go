@@ -0,0 +1,7 @@
+//go:build !unix && !windows
+
+package clip
+
+import "os/exec"
+
+func detach(cmd *exec.Cmd) {}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package clip
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts the helper in its own session, so it survives the terminal
+// closing and signals sent to the foreground process group.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -38,6 +38,7 @@ type Model struct {
 	helpModel help.Model
 
 	confirming bool
+	locked     bool
 
 	passwordInput        textinput.Model
 	confirmPasswordInput textinput.Model
@@ -70,8 +71,15 @@ func Initial() Model {
 		helpModel: helpModel,
 
 		confirming: false,
+		locked:     false,
 
 		passwordInput:        passwordInput,
 		confirmPasswordInput: confirmPasswordInput,
 	}
 }
+
+// SetLocked marks the entry screen as showing a vault that was locked, rather
+// than one being opened for the first time.
+func (m *Model) SetLocked() {
+	m.locked = true
+}
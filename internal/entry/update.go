@@ -1,6 +1,8 @@
 package entry
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -13,21 +15,31 @@ import (
 )
 
 func (m *Model) passwordComplete(createNew bool, sm *state.Model) (tea.Cmd, error) {
-	sm.Secret = passio.SecretFromString(m.passwordInput.Value())
 	if createNew {
+		if err := passio.SetMaster(sm, m.passwordInput.Value()); err != nil {
+			log.Fatalf("failed to derive key: %v", err)
+		}
 		passio.WriteStateCreds(sm)
-	}
-	if err := passio.ReadStateCreds(sm); err != nil {
-		// this error should only happen when we give the wrong password and can't decrypt
-		return state.NotificationMsg("Incorrect Password", state.MessageLevelError), err
+	} else if err := passio.ReadStateCreds(sm, m.passwordInput.Value()); err != nil {
+		message := "Incorrect Password"
+		if !errors.Is(err, passio.ErrIncorrectPassword) {
+			// the header itself is unreadable, retrying won't help
+			message = fmt.Sprintf("Could not open vault: %v", err)
+		}
+		return state.NotificationMsg(message, state.MessageLevelError), err
 	}
 	sm.Screen = state.InteractScreen
 	sm.Dirty = true
 
 	fuzzy.InitFuzzy(sm)
 
+	// nothing should keep the master password around once unlocked
+	m.passwordInput.SetValue("")
+	m.confirmPasswordInput.SetValue("")
 	m.passwordInput.Blur()
 	m.confirmPasswordInput.Blur()
+	m.confirming = false
+	m.locked = false
 
 	return nil, nil
 }
@@ -68,7 +80,7 @@ func (m *Model) Update(msg tea.Msg, sm *state.Model) tea.Cmd {
 				}
 			} else {
 				// first entry, check which scenario we're in
-				if _, err := os.Stat(uconst.DataFileName); err == nil {
+				if _, err := os.Stat(uconst.DataFilePath); err == nil {
 					// data exists, try decrypting
 					pcCmd, err := m.passwordComplete(false, sm)
 					if err != nil {
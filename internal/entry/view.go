@@ -16,5 +16,10 @@ func (m *Model) View() string {
 			m.confirmPasswordInput.View(),
 		)
 	}
+	if m.locked {
+		view += fmt.Sprintf("\n%v\n",
+			uconst.TextStyle.Render("Locked after inactivity"),
+		)
+	}
 	return uconst.ViewStyle.Render(view)
 }
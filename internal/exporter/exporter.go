@@ -0,0 +1,20 @@
+// Package exporter writes entries out in the formats of other tools.
+package exporter
+
+import (
+	"strings"
+
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/totp"
+)
+
+// otpURI is the totp secret of the entry as an otpauth URI, which is what
+// other tools expect rather than a bare secret.
+func otpURI(ci state.CredInfo) string {
+	key, err := totp.Parse(ci.TOTP)
+	if err != nil || strings.HasPrefix(strings.ToLower(ci.TOTP), "otpauth:") {
+		return ci.TOTP
+	}
+	key.Issuer, key.Account = ci.Source, ci.Username
+	return key.URI()
+}
@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dismint/dispass/internal/kdbx"
+	"github.com/dismint/dispass/internal/state"
+)
+
+// KDBX writes the entries as a KeePass database. The first tag of an entry
+// picks its group, split into subgroups on slashes, and the rest stay tags,
+// which is the reverse of how importing maps groups.
+func KDBX(w io.Writer, creds map[string]state.CredInfo, password string, opts kdbx.Options) error {
+	db := &kdbx.Database{
+		Name: "dispass",
+		Root: kdbx.Group{UUID: kdbx.NewUUID(), Name: "dispass"},
+	}
+	for _, id := range state.SortedIDs(creds) {
+		ci := creds[id]
+		var path []string
+		tags := ci.Tags
+		if len(tags) > 0 {
+			path = strings.FieldsFunc(tags[0], func(r rune) bool { return r == '/' })
+			tags = tags[1:]
+		}
+		group := subgroup(&db.Root, path)
+		group.Entries = append(group.Entries, kdbxEntry(ci, tags))
+	}
+	return kdbx.Write(w, db, password, opts)
+}
+
+// subgroup finds or creates the group at path below parent.
+func subgroup(parent *kdbx.Group, path []string) *kdbx.Group {
+	if len(path) == 0 {
+		return parent
+	}
+	for i := range parent.Groups {
+		if parent.Groups[i].Name == path[0] {
+			return subgroup(&parent.Groups[i], path[1:])
+		}
+	}
+	parent.Groups = append(parent.Groups, kdbx.Group{UUID: kdbx.NewUUID(), Name: path[0]})
+	return subgroup(&parent.Groups[len(parent.Groups)-1], path[1:])
+}
+
+func kdbxEntry(ci state.CredInfo, tags []string) kdbx.Entry {
+	created := ci.Created
+	if created.IsZero() {
+		created = ci.Modified
+	}
+	entry := kdbx.Entry{
+		UUID: kdbx.NewUUID(),
+		Tags: tags,
+		Times: kdbx.Times{
+			Created:  created,
+			Modified: ci.Modified,
+			Accessed: ci.LastUsed,
+		},
+		Strings: kdbxStrings(ci, ci.Password),
+	}
+
+	// each previous password is a version of the entry, saved when the one
+	// before it was replaced, oldest first
+	for i := len(ci.History) - 1; i >= 0; i-- {
+		saved := created
+		if i+1 < len(ci.History) {
+			saved = ci.History[i+1].Replaced
+		}
+		if saved.IsZero() {
+			// older entries have no creation time, this at least keeps the order
+			saved = ci.History[i].Replaced
+		}
+		entry.History = append(entry.History, kdbx.Entry{
+			Times:   kdbx.Times{Created: created, Modified: saved, Accessed: saved},
+			Strings: kdbxStrings(state.CredInfo{Source: ci.Source, Username: ci.Username}, ci.History[i].Password),
+		})
+	}
+	return entry
+}
+
+func kdbxStrings(ci state.CredInfo, password string) []kdbx.String {
+	strs := []kdbx.String{
+		{Key: kdbx.KeyTitle, Value: ci.Source},
+		{Key: kdbx.KeyUserName, Value: ci.Username},
+		{Key: kdbx.KeyPassword, Value: password, Protected: true},
+		{Key: kdbx.KeyURL},
+		{Key: kdbx.KeyNotes, Value: ci.Notes},
+	}
+	used := map[string]bool{}
+	for _, s := range strs {
+		used[s.Key] = true
+	}
+	add := func(key, value string, protected bool) {
+		// custom strings need unique names that don't shadow the standard ones
+		name := key
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%v (%d)", key, n)
+		}
+		used[name] = true
+		strs = append(strs, kdbx.String{Key: name, Value: value, Protected: protected})
+	}
+
+	for i, u := range ci.URLs {
+		switch i {
+		case 0:
+			strs[3].Value = u
+		case 1:
+			add("KP2A_URL", u, false)
+		default:
+			add(fmt.Sprintf("KP2A_URL_%d", i-1), u, false)
+		}
+	}
+	if ci.TOTP != "" {
+		// keepassxc only reads otpauth URIs
+		add("otp", otpURI(ci), true)
+	}
+	for _, field := range ci.Fields {
+		add(field.Name, field.Value, field.Type == state.FieldHidden)
+	}
+	return strs
+}
@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dismint/dispass/internal/perm"
+	"github.com/dismint/dispass/internal/state"
+)
+
+// PassGPGID is the file naming the keys a password store is encrypted for
+const PassGPGID = ".gpg-id"
+
+// ReadGPGID lists the keys an existing store in dir is encrypted for, if any.
+func ReadGPGID(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, PassGPGID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// Pass writes the entries as a password store in dir, each encrypted for the
+// recipients to a file named after its source, so sources with slashes end
+// up in subdirectories. Existing files are only replaced with force, and
+// none are written unless they all can be.
+func Pass(dir string, creds map[string]state.CredInfo, recipients []string, encrypt func(plaintext []byte, recipients []string) ([]byte, error), force bool) error {
+	paths := make(map[string]string, len(creds))
+	used := make(map[string]bool, len(creds))
+	for _, id := range state.SortedIDs(creds) {
+		name := passName(creds[id].Source)
+		unique := name
+		for n := 2; used[strings.ToLower(unique)]; n++ {
+			unique = fmt.Sprintf("%v (%d)", name, n)
+		}
+		used[strings.ToLower(unique)] = true
+		paths[id] = filepath.Join(dir, filepath.FromSlash(unique)+".gpg")
+
+		if !force {
+			if _, err := os.Stat(paths[id]); err == nil {
+				return fmt.Errorf("%v already exists, use --force to overwrite it", paths[id])
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, perm.DirMode); err != nil {
+		return err
+	}
+	gpgID := filepath.Join(dir, PassGPGID)
+	if _, err := os.Stat(gpgID); errors.Is(err, fs.ErrNotExist) {
+		if err := os.WriteFile(gpgID, []byte(strings.Join(recipients, "\n")+"\n"), perm.FileMode); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range state.SortedIDs(creds) {
+		ciphertext, err := encrypt([]byte(passContent(creds[id])), recipients)
+		if err != nil {
+			return fmt.Errorf("could not encrypt %v: %w", creds[id].Source, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(paths[id]), perm.DirMode); err != nil {
+			return err
+		}
+		if err := os.WriteFile(paths[id], ciphertext, perm.FileMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// passName makes a source safe to use as a path inside the store.
+func passName(source string) string {
+	parts := make([]string, 0)
+	for _, part := range strings.Split(source, "/") {
+		part = strings.TrimSpace(part)
+		// a leading dot would hide it from pass, as it does .git
+		part = strings.TrimLeft(part, ".")
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "untitled"
+	}
+	return strings.Join(parts, "/")
+}
+
+// passContent follows the layout importing expects, the password first and
+// then key: value lines, with the notes last as they can be anything.
+func passContent(ci state.CredInfo) string {
+	lines := []string{ci.Password}
+	if ci.Username != "" {
+		lines = append(lines, "login: "+ci.Username)
+	}
+	for _, u := range ci.URLs {
+		lines = append(lines, "url: "+u)
+	}
+	if ci.TOTP != "" {
+		lines = append(lines, otpURI(ci))
+	}
+	for _, field := range ci.Fields {
+		lines = append(lines, fmt.Sprintf("%v: %v", field.Name, field.Value))
+	}
+	if ci.Notes != "" {
+		lines = append(lines, ci.Notes)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -1,86 +1,164 @@
 package fuzzy
 
 import (
-	"os"
+	"math"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search"
 	"github.com/charmbracelet/log"
 	"github.com/dismint/dispass/internal/state"
-	"github.com/dismint/dispass/internal/uconst"
 )
 
+// indexDoc is what actually gets indexed for a credential, secrets never
+// make it into the index
+type indexDoc struct {
+	Source   string
+	Username string
+	URLs     []string
+	Tags     []string
+	// Modified is left out when unknown, so no date range matches it
+	Modified *time.Time
+}
+
+func newIndexDoc(ci state.CredInfo) indexDoc {
+	doc := indexDoc{
+		Source:   ci.Source,
+		Username: ci.Username,
+		URLs:     ci.URLs,
+		Tags:     ci.Tags,
+	}
+	if !ci.Modified.IsZero() {
+		doc.Modified = &ci.Modified
+	}
+	return doc
+}
+
+// InitFuzzy builds an in-memory index over the unlocked credentials.
 func InitFuzzy(sm *state.Model) {
-	var err error
+	if sm.Index != nil {
+		sm.Index.Close()
+	}
 
-	if _, statErr := os.Stat(uconst.BleveDirName); statErr == nil {
-		sm.Index, err = bleve.Open(uconst.BleveDirName)
-		if err != nil {
-			log.Fatalf("error opening bleve index: %v", err)
-		}
-	} else if os.IsNotExist(statErr) {
-		mapping := bleve.NewIndexMapping()
-		sm.Index, err = bleve.New(uconst.BleveDirName, mapping)
-		if err != nil {
-			log.Fatalf("error creating bleve index: %v", err)
-		}
+	var err error
+	mapping := bleve.NewIndexMapping()
+	sm.Index, err = bleve.NewMemOnly(mapping)
+	if err != nil {
+		log.Fatalf("error creating bleve index: %v", err)
+	}
 
-		for key, ci := range sm.KeyToCredInfo {
-			if err := sm.Index.Index(key, ci); err != nil {
-				log.Printf("failed to index %s: %v", key, err)
-			}
+	batch := sm.Index.NewBatch()
+	for key, ci := range sm.KeyToCredInfo {
+		if err := batch.Index(key, newIndexDoc(ci)); err != nil {
+			log.Printf("failed to index %s: %v", key, err)
 		}
-	} else {
-		log.Fatalf("failed to stat bleve dir: %v", statErr)
+	}
+	if err := sm.Index.Batch(batch); err != nil {
+		log.Fatalf("error populating bleve index: %v", err)
 	}
 }
 
 func UpdateFuzzy(sm *state.Model, id string, ci state.CredInfo) {
-	sm.Index.Index(id, ci)
+	sm.Index.Index(id, newIndexDoc(ci))
 }
 func RemoveFuzzy(sm *state.Model, id string) {
 	sm.Index.Delete(id)
 }
 
-func QueryTopIDs(sm *state.Model, query string) []string {
-	lowerString := strings.ToLower(query)
+// QueryTopIDs runs a query in the language described in query.go, best
+// matches first, or every entry by source for an empty one. With byFrecency
+// entries used often and lately move up as well. Only syntax errors are
+// returned.
+func QueryTopIDs(sm *state.Model, query string, byFrecency bool) ([]string, error) {
+	parsed, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
 	var searchRequest *bleve.SearchRequest
-	if query != "" {
-		prefix := bleve.NewPrefixQuery(lowerString)
-
-		fuzzy := bleve.NewFuzzyQuery(query)
-		// bleve caps at 2, not very well documented
-		fuzzy.SetFuzziness(2)
-
-		wildcard := bleve.NewQueryStringQuery("*" + query + "*")
-
-		searchRequest = bleve.NewSearchRequest(
-			bleve.NewDisjunctionQuery(prefix, fuzzy, wildcard),
-		)
+	if parsed != nil {
+		searchRequest = bleve.NewSearchRequest(parsed.bleveQuery())
 	} else {
 		searchRequest = bleve.NewSearchRequest(bleve.NewMatchAllQuery())
 	}
 	searchRequest.Size = 10000
 	searchResult, err := sm.Index.Search(searchRequest)
-	if query == "" {
-		sort.Slice(searchResult.Hits, func(i, j int) bool {
-			secondSourceLower := strings.ToLower(
-				sm.KeyToCredInfo[searchResult.Hits[j].ID].Source,
-			)
-			firstSourceLower := strings.ToLower(
-				sm.KeyToCredInfo[searchResult.Hits[i].ID].Source,
-			)
-			return firstSourceLower < secondSourceLower
-		})
-	}
 	if err != nil {
 		log.Fatalf("failed to query: %v", err)
 	}
+	if byFrecency {
+		rankByFrecency(sm, searchResult.Hits, time.Now())
+	} else {
+		// every hit of an empty query scores the same, so it's just by source
+		hits := searchResult.Hits
+		sort.Slice(hits, func(i, j int) bool {
+			if hits[i].Score != hits[j].Score {
+				return hits[i].Score > hits[j].Score
+			}
+			return sourceLess(sm, hits[i].ID, hits[j].ID)
+		})
+	}
 
 	orderedIDs := make([]string, 0)
 	for _, result := range searchResult.Hits {
 		orderedIDs = append(orderedIDs, result.ID)
 	}
-	return orderedIDs
+	return orderedIDs, nil
+}
+
+// sourceLess orders by source, then id so entries sharing a source don't
+// swap places between searches.
+func sourceLess(sm *state.Model, firstID, secondID string) bool {
+	first, second := strings.ToLower(sm.KeyToCredInfo[firstID].Source), strings.ToLower(sm.KeyToCredInfo[secondID].Source)
+	if first != second {
+		return first < second
+	}
+	return firstID < secondID
+}
+
+// frecencyHalfLife is how long it takes an entry to lose half its frecency
+// once it stops being used
+const frecencyHalfLife = 7 * 24 * time.Hour
+
+// frecency grows with how often an entry was used, slower than the count so
+// a few entries used all day don't bury everything else, and fades with
+// how long ago that last was.
+func frecency(ci state.CredInfo, now time.Time) float64 {
+	if ci.LastUsed.IsZero() {
+		return 0
+	}
+	// used before uses were counted
+	uses := max(ci.UseCount, 1)
+	age := max(now.Sub(ci.LastUsed), 0)
+	return math.Log1p(float64(uses)) * math.Exp2(-age.Hours()/frecencyHalfLife.Hours())
+}
+
+// rankByFrecency orders hits by match score and frecency added together,
+// each scaled to the best among the hits so they weigh the same. Ties, like
+// every hit of an empty query that was never used, go by source and id.
+func rankByFrecency(sm *state.Model, hits search.DocumentMatchCollection, now time.Time) {
+	maxScore, maxFrecency := 0.0, 0.0
+	frecencies := make(map[string]float64, len(hits))
+	for _, hit := range hits {
+		frecencies[hit.ID] = frecency(sm.KeyToCredInfo[hit.ID], now)
+		maxScore = max(maxScore, hit.Score)
+		maxFrecency = max(maxFrecency, frecencies[hit.ID])
+	}
+
+	ranks := make(map[string]float64, len(hits))
+	for _, hit := range hits {
+		if maxScore > 0 {
+			ranks[hit.ID] += hit.Score / maxScore
+		}
+		if maxFrecency > 0 {
+			ranks[hit.ID] += frecencies[hit.ID] / maxFrecency
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if ranks[hits[i].ID] != ranks[hits[j].ID] {
+			return ranks[hits[i].ID] > ranks[hits[j].ID]
+		}
+		return sourceLess(sm, hits[i].ID, hits[j].ID)
+	})
 }
@@ -0,0 +1,81 @@
+package fuzzy
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+func TestFrecency(t *testing.T) {
+	now := time.Now()
+	sm := &state.Model{KeyToCredInfo: map[string]state.CredInfo{
+		"bank":   {Source: "Bank"},
+		"github": {Source: "GitHub", LastUsed: now.Add(-time.Hour), UseCount: 3},
+		"gitlab": {Source: "GitLab", LastUsed: now.Add(-time.Hour), UseCount: 40},
+		"mail":   {Source: "Mail", LastUsed: now.AddDate(0, -2, 0), UseCount: 500},
+		// used before uses were counted
+		"shop": {Source: "Shop", LastUsed: now.AddDate(0, 0, -1)},
+	}}
+	InitFuzzy(sm)
+	defer sm.Index.Close()
+
+	tests := []struct {
+		query      string
+		byFrecency bool
+		want       []string
+	}{
+		{"", false, []string{"bank", "github", "gitlab", "mail", "shop"}},
+		{"", true, []string{"gitlab", "github", "shop", "mail", "bank"}},
+		{"source:git", true, []string{"gitlab", "github"}},
+		{"source:bank OR source:shop", true, []string{"shop", "bank"}},
+	}
+	for _, test := range tests {
+		got, err := QueryTopIDs(sm, test.query, test.byFrecency)
+		if err != nil {
+			t.Errorf("QueryTopIDs(%q): %v", test.query, err)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("QueryTopIDs(%q, %v) = %v, want %v", test.query, test.byFrecency, got, test.want)
+		}
+	}
+}
+
+func TestQueryOrderIsStable(t *testing.T) {
+	sm := &state.Model{KeyToCredInfo: map[string]state.CredInfo{"home": {Source: "home"}}}
+	work := make([]string, 0)
+	for i := range 20 {
+		id := fmt.Sprintf("work%02d", i)
+		sm.KeyToCredInfo[id] = state.CredInfo{Source: []string{"work", "Work"}[i%2]}
+		work = append(work, id)
+	}
+	all := append([]string{"home"}, work...)
+
+	tests := []struct {
+		query      string
+		byFrecency bool
+		want       []string
+	}{
+		{"", false, all},
+		{"", true, all},
+		{"source:work", false, work},
+		{"source:work", true, work},
+	}
+	// the index hands back hits in the order they happened to be added
+	for range 10 {
+		InitFuzzy(sm)
+		for _, test := range tests {
+			got, err := QueryTopIDs(sm, test.query, test.byFrecency)
+			if err != nil {
+				t.Fatalf("QueryTopIDs(%q): %v", test.query, err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Fatalf("QueryTopIDs(%q, %v) = %v, want %v", test.query, test.byFrecency, got, test.want)
+			}
+		}
+	}
+	sm.Index.Close()
+}
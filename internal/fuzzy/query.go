@@ -0,0 +1,433 @@
+package fuzzy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search/query"
+)
+
+// The query language: words match any field by prefix, substring or a typo
+// or two, "quoted phrases" match the words in order, and either can be
+// limited to a field as source:git or tag:"two words". modified:>2024-01-01
+// compares dates with <, <=, >, >= or =, down to the year, month or day.
+// Terms next to each other must all match unless joined by OR, which binds
+// looser than AND, - negates a term and parentheses group.
+
+// searchFields maps the field prefixes to the indexed fields
+var searchFields = map[string]string{
+	"source":   "Source",
+	"user":     "Username",
+	"username": "Username",
+	"tag":      "Tags",
+	"tags":     "Tags",
+	"url":      "URLs",
+	"urls":     "URLs",
+	"modified": "Modified",
+}
+
+// dateFields can only be compared against dates
+var dateFields = map[string]bool{"Modified": true}
+
+type nodeKind int
+
+const (
+	nodeWord nodeKind = iota
+	nodePhrase
+	nodeDate
+	nodeAnd
+	nodeOr
+	nodeNot
+)
+
+type node struct {
+	kind nodeKind
+	// field is empty for every field
+	field string
+	text  string
+	// a date matches from start until end
+	op         string
+	start, end time.Time
+	children   []*node
+}
+
+// String writes the node out in prefix form, which is only for tests.
+func (n *node) String() string {
+	prefix := ""
+	if n.field != "" {
+		prefix = n.field + ":"
+	}
+	switch n.kind {
+	case nodeWord:
+		return prefix + n.text
+	case nodePhrase:
+		return prefix + fmt.Sprintf("%q", n.text)
+	case nodeDate:
+		return prefix + n.op + n.text
+	}
+	parts := make([]string, 0, len(n.children))
+	for _, child := range n.children {
+		parts = append(parts, child.String())
+	}
+	name := map[nodeKind]string{nodeAnd: "and", nodeOr: "or", nodeNot: "not"}[n.kind]
+	return "(" + name + " " + strings.Join(parts, " ") + ")"
+}
+
+// SyntaxError is a query that could not be parsed, Pos counts runes.
+type SyntaxError struct {
+	Pos     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%v at %d", e.Message, e.Pos+1)
+}
+
+type tokenKind int
+
+const (
+	tokenTerm tokenKind = iota
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenOpen
+	tokenClose
+	tokenEOF
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	term *node
+}
+
+func isTermEnd(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+// lex splits the query into tokens, terms are parsed as they are read.
+func lex(rs []rune) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, pos: i})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, pos: i})
+			i++
+			continue
+		case r == '-':
+			tokens = append(tokens, token{kind: tokenNot, pos: i})
+			i++
+			continue
+		}
+
+		start := i
+		field := ""
+		// a known field name up to the colon scopes the term, anything else
+		// like https: is part of the word
+		if colon := indexRune(rs[i:], ':'); colon > 0 && !hasTermEnd(rs[i:i+colon]) {
+			if name, ok := searchFields[strings.ToLower(string(rs[i:i+colon]))]; ok {
+				field = name
+				i += colon + 1
+			}
+		}
+
+		var term *node
+		if i < len(rs) && rs[i] == '"' {
+			end := indexRune(rs[i+1:], '"')
+			if end < 0 {
+				return nil, &SyntaxError{Pos: i, Message: "unterminated quote"}
+			}
+			term = &node{kind: nodePhrase, field: field, text: string(rs[i+1 : i+1+end])}
+			i += end + 2
+		} else {
+			end := i
+			for end < len(rs) && !isTermEnd(rs[end]) {
+				end++
+			}
+			term = &node{kind: nodeWord, field: field, text: string(rs[i:end])}
+			i = end
+		}
+
+		switch {
+		case dateFields[field]:
+			if err := parseDate(term); err != nil {
+				return nil, &SyntaxError{Pos: start, Message: err.Error()}
+			}
+		case term.text == "" && field != "":
+			return nil, &SyntaxError{Pos: start, Message: "nothing to match after " + string(rs[start:i])}
+		case term.kind == nodeWord && field == "" && term.text == "AND":
+			tokens = append(tokens, token{kind: tokenAnd, pos: start})
+			continue
+		case term.kind == nodeWord && field == "" && term.text == "OR":
+			tokens = append(tokens, token{kind: tokenOr, pos: start})
+			continue
+		}
+		tokens = append(tokens, token{kind: tokenTerm, pos: start, term: term})
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(rs)}), nil
+}
+
+func indexRune(rs []rune, r rune) int {
+	for i := range rs {
+		if rs[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasTermEnd(rs []rune) bool {
+	for _, r := range rs {
+		if isTermEnd(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// dateLayouts are tried in turn, each matching a period as long as its
+// smallest unit
+var dateLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// parseDate reads a comparison and a date into the period a date term
+// matches.
+func parseDate(term *node) error {
+	if term.kind == nodePhrase {
+		return fmt.Errorf("dates can't be quoted")
+	}
+	text := term.text
+	term.kind = nodeDate
+	term.op = "="
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(text, op) {
+			term.op = op
+			text = text[len(op):]
+			break
+		}
+	}
+	term.text = text
+
+	for _, candidate := range dateLayouts {
+		day, err := time.ParseInLocation(candidate.layout, text, time.Local)
+		if err != nil {
+			continue
+		}
+		// bleve keeps dates as nanoseconds
+		if day.Year() < 1678 || day.Year() > 2261 {
+			return fmt.Errorf("%v is out of range", text)
+		}
+		next := candidate.next(day)
+		switch term.op {
+		case "=":
+			term.start, term.end = day, next
+		case ">":
+			term.start = next
+		case ">=":
+			term.start = day
+		case "<":
+			term.end = day
+		case "<=":
+			term.end = next
+		}
+		return nil
+	}
+	return fmt.Errorf("expected a date like 2024-01-31, not %q", text)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// parse reads a whole query, nil for one with no terms.
+func parse(q string) (*node, error) {
+	tokens, err := lex([]rune(q))
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: t.pos, Message: "unexpected )"}
+	}
+	return n, nil
+}
+
+func (p *parser) or() (*node, error) {
+	n, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	children := []*node{n}
+	for p.peek().kind == tokenOr {
+		p.next()
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &node{kind: nodeOr, children: children}, nil
+}
+
+func (p *parser) and() (*node, error) {
+	children := make([]*node, 0)
+	for {
+		switch t := p.peek(); t.kind {
+		case tokenOr, tokenClose, tokenEOF:
+			if len(children) == 0 {
+				return nil, p.missing(t)
+			}
+			if len(children) == 1 {
+				return children[0], nil
+			}
+			return &node{kind: nodeAnd, children: children}, nil
+		case tokenAnd:
+			if len(children) == 0 {
+				return nil, &SyntaxError{Pos: t.pos, Message: "nothing before AND"}
+			}
+			p.next()
+			if next := p.peek(); next.kind == tokenOr || next.kind == tokenClose || next.kind == tokenEOF {
+				return nil, &SyntaxError{Pos: t.pos, Message: "nothing after AND"}
+			}
+		}
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+}
+
+// missing explains what is wrong with an empty side of OR or parentheses.
+func (p *parser) missing(t token) error {
+	switch {
+	case t.kind == tokenOr:
+		return &SyntaxError{Pos: t.pos, Message: "nothing before OR"}
+	case p.pos > 0 && p.tokens[p.pos-1].kind == tokenOr:
+		return &SyntaxError{Pos: p.tokens[p.pos-1].pos, Message: "nothing after OR"}
+	case t.kind == tokenClose && p.pos > 0 && p.tokens[p.pos-1].kind == tokenOpen:
+		return &SyntaxError{Pos: t.pos, Message: "empty parentheses"}
+	case t.kind == tokenClose:
+		return &SyntaxError{Pos: t.pos, Message: "unexpected )"}
+	}
+	return &SyntaxError{Pos: t.pos, Message: "unexpected end of query"}
+}
+
+func (p *parser) unary() (*node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNot:
+		if next := p.peek(); next.kind != tokenTerm && next.kind != tokenOpen && next.kind != tokenNot {
+			return nil, &SyntaxError{Pos: t.pos, Message: "nothing to negate"}
+		}
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeNot, children: []*node{n}}, nil
+	case tokenOpen:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenClose {
+			return nil, &SyntaxError{Pos: t.pos, Message: "unclosed ("}
+		}
+		return n, nil
+	case tokenTerm:
+		return t.term, nil
+	}
+	return nil, &SyntaxError{Pos: t.pos, Message: "unexpected )"}
+}
+
+// bleveQuery turns the parsed query into one bleve can run.
+func (n *node) bleveQuery() query.Query {
+	switch n.kind {
+	case nodeWord:
+		return wordQuery(n.field, n.text)
+	case nodePhrase:
+		phrase := bleve.NewMatchPhraseQuery(n.text)
+		if n.field != "" {
+			phrase.SetField(n.field)
+		}
+		return phrase
+	case nodeDate:
+		inclusive, exclusive := true, false
+		date := bleve.NewDateRangeInclusiveQuery(n.start, n.end, &inclusive, &exclusive)
+		date.SetField(n.field)
+		return date
+	case nodeNot:
+		// bleve needs something to take the negation away from
+		negation := bleve.NewBooleanQuery()
+		negation.AddMust(bleve.NewMatchAllQuery())
+		negation.AddMustNot(n.children[0].bleveQuery())
+		return negation
+	}
+	children := make([]query.Query, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child.bleveQuery())
+	}
+	if n.kind == nodeAnd {
+		return bleve.NewConjunctionQuery(children...)
+	}
+	return bleve.NewDisjunctionQuery(children...)
+}
+
+// wordQuery matches a word loosely, by prefix, substring or a typo or two.
+func wordQuery(field, word string) query.Query {
+	lower := strings.ToLower(word)
+	prefix := bleve.NewPrefixQuery(lower)
+
+	fuzzy := bleve.NewFuzzyQuery(lower)
+	// bleve caps at 2, not very well documented
+	fuzzy.SetFuzziness(2)
+
+	wildcard := bleve.NewWildcardQuery("*" + lower + "*")
+
+	if field != "" {
+		prefix.SetField(field)
+		fuzzy.SetField(field)
+		wildcard.SetField(field)
+	}
+	return bleve.NewDisjunctionQuery(prefix, fuzzy, wildcard)
+}
@@ -0,0 +1,180 @@
+package fuzzy
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "<nil>"},
+		{"   ", "<nil>"},
+		{"git", "git"},
+		{"git hub", "(and git hub)"},
+		{"git AND hub", "(and git hub)"},
+		{"git OR hub", "(or git hub)"},
+		{"a b OR c", "(or (and a b) c)"},
+		{"a OR b c", "(or a (and b c))"},
+		{"a AND (b OR c)", "(and a (or b c))"},
+		{"((a))", "a"},
+		{"-a", "(not a)"},
+		{"--a", "(not (not a))"},
+		{"a -(b OR c)", "(and a (not (or b c)))"},
+		{"source:git", "Source:git"},
+		{"SOURCE:git", "Source:git"},
+		{"user:me tag:work url:example.com", "(and Username:me Tags:work URLs:example.com)"},
+		{"username:me tags:work urls:x", "(and Username:me Tags:work URLs:x)"},
+		{`"two words"`, `"two words"`},
+		{`tag:"two words" -source:"old one"`, `(and Tags:"two words" (not Source:"old one"))`},
+		{`a"b c"`, `(and a "b c")`},
+		// only known fields scope a term
+		{"https://example.com", "https://example.com"},
+		{"localhost:8080", "localhost:8080"},
+		{"foo-bar", "foo-bar"},
+		// keywords are upper case, anything else is a word
+		{"a and b or c", "(and a and b or c)"},
+		{"modified:2024-01-31", "Modified:=2024-01-31"},
+		{"modified:>2024-01-01", "Modified:>2024-01-01"},
+		{"modified:>=2024-01 modified:<2025", "(and Modified:>=2024-01 Modified:<2025)"},
+		{"modified:<=2024-06-30 OR modified:=2023", "(or Modified:<=2024-06-30 Modified:=2023)"},
+	}
+	for _, test := range tests {
+		n, err := parse(test.query)
+		if err != nil {
+			t.Errorf("parse(%q): %v", test.query, err)
+			continue
+		}
+		got := "<nil>"
+		if n != nil {
+			got = n.String()
+		}
+		if got != test.want {
+			t.Errorf("parse(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		query      string
+		start, end time.Time
+	}{
+		{"modified:2024-01-31", day(2024, 1, 31), day(2024, 2, 1)},
+		{"modified:=2024-02", day(2024, 2, 1), day(2024, 3, 1)},
+		{"modified:2024", day(2024, 1, 1), day(2025, 1, 1)},
+		{"modified:>2024-01-01", day(2024, 1, 2), time.Time{}},
+		{"modified:>=2024-01", day(2024, 1, 1), time.Time{}},
+		{"modified:<2024", time.Time{}, day(2024, 1, 1)},
+		{"modified:<=2024-12", time.Time{}, day(2025, 1, 1)},
+	}
+	for _, test := range tests {
+		n, err := parse(test.query)
+		if err != nil {
+			t.Errorf("parse(%q): %v", test.query, err)
+			continue
+		}
+		if !n.start.Equal(test.start) || !n.end.Equal(test.end) {
+			t.Errorf("parse(%q) matches %v until %v, want %v until %v", test.query, n.start, n.end, test.start, test.end)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{`"open`, 0},
+		{`source:"open`, 7},
+		{"source:", 0},
+		{"a tag: b", 2},
+		{"-", 0},
+		{"a -", 2},
+		{"OR a", 0},
+		{"a OR", 2},
+		{"a OR OR b", 5},
+		{"AND a", 0},
+		{"a AND", 2},
+		{"a AND OR b", 2},
+		{"()", 1},
+		{"(a", 0},
+		{"a)", 1},
+		{"a (b OR) c", 5},
+		{"modified:yesterday", 0},
+		{"modified:2024-13-01", 0},
+		{"modified:>", 0},
+		{`modified:"2024"`, 0},
+		{"modified:1200", 0},
+	}
+	for _, test := range tests {
+		n, err := parse(test.query)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("parse(%q) = %v, %v, want a syntax error", test.query, n, err)
+			continue
+		}
+		if syntaxErr.Pos != test.pos {
+			t.Errorf("parse(%q): %v, want it at %d", test.query, err, test.pos+1)
+		}
+	}
+}
+
+func TestQueryTopIDs(t *testing.T) {
+	sm := &state.Model{KeyToCredInfo: map[string]state.CredInfo{
+		"github": {
+			Source: "GitHub", Username: "alice", Tags: []string{"work"},
+			URLs: []string{"https://github.com"}, Modified: time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local),
+		},
+		"gitlab": {
+			Source: "GitLab", Username: "bob", Tags: []string{"side project"},
+			Modified: time.Date(2023, 6, 1, 12, 0, 0, 0, time.Local),
+		},
+		"bank": {Source: "Bank", Username: "alice git"},
+	}}
+	InitFuzzy(sm)
+	defer sm.Index.Close()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"bank", "github", "gitlab"}},
+		{"git", []string{"bank", "github", "gitlab"}},
+		{"source:git", []string{"github", "gitlab"}},
+		{"source:git -tag:work", []string{"gitlab"}},
+		{"user:alice", []string{"bank", "github"}},
+		{"user:alice source:git", []string{"github"}},
+		{`tag:"side project"`, []string{"gitlab"}},
+		{"url:github.com", []string{"github"}},
+		{"source:bank OR tag:work", []string{"bank", "github"}},
+		{"modified:>2024-01-01", []string{"github"}},
+		{"modified:<2024", []string{"gitlab"}},
+		// unknown dates are never in range
+		{"-modified:>=2000", []string{"bank"}},
+	}
+	for _, test := range tests {
+		got, err := QueryTopIDs(sm, test.query, false)
+		if err != nil {
+			t.Errorf("QueryTopIDs(%q): %v", test.query, err)
+			continue
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, test.want) {
+			t.Errorf("QueryTopIDs(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+
+	if _, err := QueryTopIDs(sm, `source:"open`, false); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
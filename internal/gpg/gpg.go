@@ -0,0 +1,76 @@
+// Package gpg runs the gpg binary, passwords are never handled here as
+// gpg-agent asks for them itself.
+package gpg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+// the same options pass uses
+var baseArgs = []string{"--quiet", "--yes", "--compress-algo=none", "--no-encrypt-to", "--batch"}
+
+func run(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(uconst.GPGBinary, append(append([]string{}, baseArgs...), args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("could not find %v, set pass.gpg in the config", uconst.GPGBinary)
+		}
+		if message := lastLine(stderr.String()); message != "" {
+			return nil, errors.New(message)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// Decrypt decrypts a file, preferring the configured key when it holds
+// several secret keys.
+func Decrypt(path string) ([]byte, error) {
+	return run(nil, decryptArgs(path)...)
+}
+
+// DecryptCommand decrypts a file and throws the plaintext away. Run with the
+// terminal to itself, it lets gpg-agent ask for the passphrase and cache it
+// for Decrypt.
+func DecryptCommand(path string) *exec.Cmd {
+	cmd := exec.Command(uconst.GPGBinary, append(append([]string{}, baseArgs...), decryptArgs(path)...)...)
+	cmd.Stdout = io.Discard
+	return cmd
+}
+
+func decryptArgs(path string) []string {
+	args := make([]string, 0)
+	if uconst.GPGKey != "" {
+		args = append(args, "--try-secret-key", uconst.GPGKey)
+	}
+	return append(args, "--decrypt", path)
+}
+
+// Encrypt encrypts for every recipient, which can be anything gpg accepts as
+// a key id.
+func Encrypt(plaintext []byte, recipients []string) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no gpg key to encrypt for")
+	}
+	args := make([]string, 0, 2*len(recipients)+1)
+	for _, recipient := range recipients {
+		args = append(args, "--recipient", recipient)
+	}
+	return run(plaintext, append(args, "--encrypt")...)
+}
@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+// bitwarden item and field types
+const (
+	bitwardenLogin      = 1
+	bitwardenSecureNote = 2
+	bitwardenCard       = 3
+	bitwardenIdentity   = 4
+
+	bitwardenFieldText    = 0
+	bitwardenFieldHidden  = 1
+	bitwardenFieldBoolean = 2
+	bitwardenFieldLinked  = 3
+)
+
+type bitwardenExport struct {
+	Encrypted bool `json:"encrypted"`
+	Folders   []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"folders"`
+	Items []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	Type         int       `json:"type"`
+	Name         string    `json:"name"`
+	Notes        string    `json:"notes"`
+	FolderID     string    `json:"folderId"`
+	CreationDate time.Time `json:"creationDate"`
+	RevisionDate time.Time `json:"revisionDate"`
+	Fields       []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Type  int    `json:"type"`
+	} `json:"fields"`
+	Login *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+		URIs     []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+	PasswordHistory []struct {
+		Password     string    `json:"password"`
+		LastUsedDate time.Time `json:"lastUsedDate"`
+	} `json:"passwordHistory"`
+	Card     map[string]any `json:"card"`
+	Identity map[string]any `json:"identity"`
+}
+
+// sensitive card and identity values become hidden fields
+var bitwardenHidden = map[string]bool{
+	"number":         true,
+	"code":           true,
+	"ssn":            true,
+	"passportNumber": true,
+	"licenseNumber":  true,
+}
+
+func parseBitwarden(data []byte) (Result, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return Result{}, fmt.Errorf("failed to read bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return Result{}, errors.New("encrypted bitwarden exports are not supported, export as unencrypted json")
+	}
+
+	folders := make(map[string]string)
+	for _, folder := range export.Folders {
+		folders[folder.ID] = folder.Name
+	}
+
+	var result Result
+	for i, item := range export.Items {
+		record := Record{
+			Where: fmt.Sprintf("item %d %q", i+1, item.Name),
+			CredInfo: state.CredInfo{
+				Source:   item.Name,
+				Notes:    item.Notes,
+				Tags:     folderTag(folders[item.FolderID]),
+				Created:  item.CreationDate,
+				Modified: item.RevisionDate,
+			},
+		}
+		ci := &record.CredInfo
+
+		switch item.Type {
+		case bitwardenLogin:
+			if item.Login != nil {
+				ci.Username = item.Login.Username
+				ci.Password = item.Login.Password
+				ci.TOTP = item.Login.TOTP
+				for _, uri := range item.Login.URIs {
+					if uri.URI != "" {
+						ci.URLs = append(ci.URLs, uri.URI)
+					}
+				}
+			}
+		case bitwardenSecureNote:
+		case bitwardenCard:
+			ci.Fields = append(ci.Fields, bitwardenObjectFields(item.Card)...)
+		case bitwardenIdentity:
+			ci.Fields = append(ci.Fields, bitwardenObjectFields(item.Identity)...)
+		default:
+			result.skip(record.Where, fmt.Sprintf("unknown item type %d", item.Type))
+			continue
+		}
+
+		for _, field := range item.Fields {
+			fieldType := state.FieldText
+			switch field.Type {
+			case bitwardenFieldHidden:
+				fieldType = state.FieldHidden
+			case bitwardenFieldText, bitwardenFieldBoolean:
+			case bitwardenFieldLinked:
+				// linked fields only point at another field of the item
+				record.Unmapped = append(record.Unmapped, "linked field "+field.Name)
+				continue
+			}
+			ci.Fields = append(ci.Fields, state.CustomField{
+				Name:  field.Name,
+				Type:  fieldType,
+				Value: field.Value,
+			})
+		}
+
+		for _, previous := range item.PasswordHistory {
+			ci.History = append(ci.History, state.PreviousPassword{
+				Password: previous.Password,
+				Replaced: previous.LastUsedDate,
+			})
+		}
+
+		result.add(record)
+	}
+	return result, nil
+}
+
+// bitwardenObjectFields turns the properties of a card or identity into
+// custom fields, in a stable order.
+func bitwardenObjectFields(object map[string]any) []state.CustomField {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]state.CustomField, 0)
+	for _, name := range names {
+		value, ok := object[name].(string)
+		if !ok || value == "" {
+			continue
+		}
+		fieldType := state.FieldText
+		if bitwardenHidden[name] {
+			fieldType = state.FieldHidden
+		}
+		fields = append(fields, state.CustomField{Name: name, Type: fieldType, Value: value})
+	}
+	return fields
+}
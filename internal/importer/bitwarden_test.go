@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+func TestParseBitwarden(t *testing.T) {
+	result := parseOnly(t, FormatBitwardenJSON, `{
+  "encrypted": false,
+  "folders": [{"id": "f1", "name": "Work/Dev"}],
+  "items": [
+    {
+      "type": 1, "name": "GitHub", "notes": "main account", "folderId": "f1",
+      "creationDate": "2023-01-01T09:00:00Z", "revisionDate": "2024-03-01T10:00:00Z",
+      "login": {
+        "username": "alice", "password": "hunter2", "totp": "JBSWY3DPEHPK3PXP",
+        "uris": [{"uri": "https://github.com"}, {"uri": ""}]
+      },
+      "fields": [
+        {"name": "pin", "value": "1234", "type": 1},
+        {"name": "team", "value": "core", "type": 0},
+        {"name": "2fa", "value": "true", "type": 2},
+        {"name": "fill password", "value": null, "type": 3}
+      ],
+      "passwordHistory": [{"password": "hunter1", "lastUsedDate": "2023-06-01T00:00:00Z"}]
+    },
+    {
+      "type": 3, "name": "Visa",
+      "card": {"cardholderName": "Alice", "number": "4111111111111111", "code": "123", "expYear": "2030", "brand": null}
+    },
+    {
+      "type": 4, "name": "Me",
+      "identity": {"firstName": "Alice", "ssn": "123-45-6789", "passportNumber": "X123"}
+    },
+    {"type": 2, "name": "Empty note"},
+    {"type": 9, "name": "From the future", "notes": "?"}
+  ]
+}`)
+
+	want := []Record{
+		{
+			Where: `item 1 "GitHub"`,
+			CredInfo: state.CredInfo{
+				Source: "GitHub", Username: "alice", Password: "hunter2", TOTP: "JBSWY3DPEHPK3PXP",
+				URLs: []string{"https://github.com"}, Notes: "main account", Tags: []string{"Work/Dev"},
+				Fields: []state.CustomField{
+					{Name: "pin", Type: state.FieldHidden, Value: "1234"},
+					{Name: "team", Type: state.FieldText, Value: "core"},
+					{Name: "2fa", Type: state.FieldText, Value: "true"},
+				},
+				History: []state.PreviousPassword{
+					{Password: "hunter1", Replaced: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)},
+				},
+				Created:  time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC),
+				Modified: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			},
+			Unmapped: []string{"linked field fill password"},
+		},
+		{
+			Where: `item 2 "Visa"`,
+			CredInfo: state.CredInfo{Source: "Visa", Fields: []state.CustomField{
+				{Name: "cardholderName", Type: state.FieldText, Value: "Alice"},
+				{Name: "code", Type: state.FieldHidden, Value: "123"},
+				{Name: "expYear", Type: state.FieldText, Value: "2030"},
+				{Name: "number", Type: state.FieldHidden, Value: "4111111111111111"},
+			}},
+		},
+		{
+			Where: `item 3 "Me"`,
+			CredInfo: state.CredInfo{Source: "Me", Fields: []state.CustomField{
+				{Name: "firstName", Type: state.FieldText, Value: "Alice"},
+				{Name: "passportNumber", Type: state.FieldHidden, Value: "X123"},
+				{Name: "ssn", Type: state.FieldHidden, Value: "123-45-6789"},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(result.Records, want) {
+		t.Errorf("got %+v, want %+v", result.Records, want)
+	}
+
+	wantSkipped := []Skip{
+		{Where: `item 4 "Empty note"`, Reason: "nothing to import"},
+		{Where: `item 5 "From the future"`, Reason: "unknown item type 9"},
+	}
+	if !reflect.DeepEqual(result.Skipped, wantSkipped) {
+		t.Errorf("skipped: got %+v, want %+v", result.Skipped, wantSkipped)
+	}
+}
+
+func TestParseBitwardenEncrypted(t *testing.T) {
+	if _, err := Parse(FormatBitwardenJSON, []byte(`{"encrypted": true, "items": []}`)); err == nil {
+		t.Errorf("expected an error for an encrypted export")
+	}
+}
@@ -0,0 +1,227 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+// csvRow is a record keyed by lowercased header
+type csvRow struct {
+	line    int
+	columns map[string]string
+	used    map[string]bool
+}
+
+func (r *csvRow) get(names ...string) string {
+	for _, name := range names {
+		if value, ok := r.columns[name]; ok {
+			r.used[name] = true
+			if value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// ignore marks columns that are known but deliberately dropped.
+func (r *csvRow) ignore(names ...string) {
+	for _, name := range names {
+		r.used[name] = true
+	}
+}
+
+// unmapped lists the filled in columns nothing read.
+func (r *csvRow) unmapped() []string {
+	unmapped := make([]string, 0)
+	for name, value := range r.columns {
+		if !r.used[name] && strings.TrimSpace(value) != "" {
+			unmapped = append(unmapped, name)
+		}
+	}
+	sort.Strings(unmapped)
+	return unmapped
+}
+
+func (r *csvRow) where() string {
+	return fmt.Sprintf("line %d", r.line)
+}
+
+// readCSV reads a csv with a header row, requiring the given columns.
+func readCSV(data []byte, required ...string) ([]*csvRow, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	for _, name := range required {
+		found := false
+		for _, column := range header {
+			found = found || column == name
+		}
+		if !found {
+			return nil, fmt.Errorf("csv has no %q column, is this the right format?", name)
+		}
+	}
+
+	rows := make([]*csvRow, 0)
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		row := &csvRow{
+			line:    line,
+			columns: make(map[string]string, len(header)),
+			used:    make(map[string]bool),
+		}
+		for i, value := range record {
+			if i < len(header) {
+				row.columns[header[i]] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseLastPass(data []byte) (Result, error) {
+	rows, err := readCSV(data, "url", "username", "password", "name")
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, row := range rows {
+		ci := state.CredInfo{
+			Source:   row.get("name"),
+			Username: row.get("username"),
+			Password: row.get("password"),
+			TOTP:     row.get("totp"),
+			Notes:    row.get("extra"),
+			Tags:     folderTag(row.get("grouping")),
+		}
+		row.ignore("fav")
+		// secure notes have this placeholder instead of a url
+		if u := row.get("url"); u != "" && u != "http://sn" {
+			ci.URLs = []string{u}
+		}
+		result.add(Record{Where: row.where(), CredInfo: ci, Unmapped: row.unmapped()})
+	}
+	return result, nil
+}
+
+func parseKeePassXC(data []byte) (Result, error) {
+	rows, err := readCSV(data, "title", "username", "password")
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, row := range rows {
+		// everything sits in the root group, which isn't worth a tag
+		group := row.get("group")
+		if group == "Root" || strings.HasPrefix(group, "Root/") {
+			group = strings.TrimPrefix(group, "Root")
+		}
+		ci := state.CredInfo{
+			Source:   row.get("title"),
+			Username: row.get("username"),
+			Password: row.get("password"),
+			TOTP:     row.get("totp"),
+			Notes:    row.get("notes"),
+			Tags:     folderTag(group),
+			Created:  parseTime(row.get("created")),
+			Modified: parseTime(row.get("last modified")),
+		}
+		row.ignore("icon")
+		if u := row.get("url"); u != "" {
+			ci.URLs = []string{u}
+		}
+		result.add(Record{Where: row.where(), CredInfo: ci, Unmapped: row.unmapped()})
+	}
+	return result, nil
+}
+
+func parseChrome(data []byte) (Result, error) {
+	rows, err := readCSV(data, "url", "username", "password")
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, row := range rows {
+		ci := state.CredInfo{
+			Source:   row.get("name"),
+			Username: row.get("username"),
+			Password: row.get("password"),
+			Notes:    row.get("note", "notes"),
+		}
+		if u := row.get("url"); u != "" {
+			ci.URLs = []string{u}
+		}
+		result.add(Record{Where: row.where(), CredInfo: ci, Unmapped: row.unmapped()})
+	}
+	return result, nil
+}
+
+func parseFirefox(data []byte) (Result, error) {
+	rows, err := readCSV(data, "url", "username", "password")
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, row := range rows {
+		ci := state.CredInfo{
+			Username: row.get("username"),
+			Password: row.get("password"),
+			Created:  parseMillis(row.get("timecreated")),
+			Modified: parseMillis(row.get("timepasswordchanged")),
+			LastUsed: parseMillis(row.get("timelastused")),
+		}
+		row.ignore("guid", "formactionorigin")
+		if u := row.get("url"); u != "" {
+			ci.URLs = []string{u}
+		}
+		result.add(Record{Where: row.where(), CredInfo: ci, Unmapped: row.unmapped()})
+	}
+	return result, nil
+}
+
+// parseTime reads the timestamps of the usual exports, zero if it can't.
+func parseTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func parseMillis(value string) time.Time {
+	millis, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || millis <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(millis)
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+// parseOnly parses data in format, failing the test on error.
+func parseOnly(t *testing.T, format Format, data string) Result {
+	t.Helper()
+	result, err := Parse(format, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestParseLastPass(t *testing.T) {
+	result := parseOnly(t, FormatLastPassCSV, "\xef\xbb\xbfurl,username,password,totp,extra,name,grouping,fav,color\n"+
+		"https://github.com/login,alice,hunter2,JBSWY3DPEHPK3PXP,,GitHub,Work\\Dev,1,\n"+
+		"http://sn,,,,\"NoteType:Server\nHostname:db\",DB notes,,0,blue\n"+
+		",,,,,Empty,,0,\n")
+
+	want := []Record{
+		{Where: "line 2", CredInfo: state.CredInfo{
+			Source: "GitHub", Username: "alice", Password: "hunter2", TOTP: "JBSWY3DPEHPK3PXP",
+			URLs: []string{"https://github.com/login"}, Tags: []string{"Work/Dev"},
+		}, Unmapped: []string{}},
+		// secure notes keep no url
+		{Where: "line 3", CredInfo: state.CredInfo{
+			Source: "DB notes", Notes: "NoteType:Server\nHostname:db",
+		}, Unmapped: []string{"color"}},
+	}
+	if !reflect.DeepEqual(result.Records, want) {
+		t.Errorf("got %+v, want %+v", result.Records, want)
+	}
+	if want := []Skip{{Where: "line 5", Reason: "nothing to import"}}; !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("skipped: got %+v, want %+v", result.Skipped, want)
+	}
+}
+
+func TestParseKeePassXC(t *testing.T) {
+	result := parseOnly(t, FormatKeePassXCCSV, `"Group","Title","Username","Password","URL","Notes","TOTP","Icon","Last Modified","Created"
+"Root","Top","me","pw1","","","","0","2024-03-01T10:00:00Z","2023-01-01T09:00:00Z"
+"Root/Work/Mail","Mail","me","pw2","mail.example.com","","","0","",""
+"Rootkits","Lab","me","pw3","","","","0","",""
+`)
+
+	got := make(map[string]state.CredInfo)
+	for _, record := range result.Records {
+		got[record.CredInfo.Source] = record.CredInfo
+	}
+	// the root group everything sits in is left out
+	if tags := got["Top"].Tags; tags != nil {
+		t.Errorf("root group: got tags %v", tags)
+	}
+	if tags := got["Mail"].Tags; !reflect.DeepEqual(tags, []string{"Work/Mail"}) {
+		t.Errorf("nested group: got tags %v", tags)
+	}
+	if tags := got["Lab"].Tags; !reflect.DeepEqual(tags, []string{"Rootkits"}) {
+		t.Errorf("group starting with root: got tags %v", tags)
+	}
+	if created, modified := got["Top"].Created, got["Top"].Modified; !created.Equal(time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC)) ||
+		!modified.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("times: got %v and %v", created, modified)
+	}
+	if urls := got["Mail"].URLs; !reflect.DeepEqual(urls, []string{"mail.example.com"}) {
+		t.Errorf("urls: got %v", urls)
+	}
+}
+
+func TestParseChrome(t *testing.T) {
+	result := parseOnly(t, FormatChromeCSV, "name,url,username,password,note\n"+
+		",https://www.example.com/login,bob,pw,remember me\n")
+
+	want := []Record{{Where: "line 2", CredInfo: state.CredInfo{
+		// named after the host when the export has no name
+		Source: "example.com", Username: "bob", Password: "pw", Notes: "remember me",
+		URLs: []string{"https://www.example.com/login"},
+	}, Unmapped: []string{}}}
+	if !reflect.DeepEqual(result.Records, want) {
+		t.Errorf("got %+v, want %+v", result.Records, want)
+	}
+}
+
+func TestParseFirefox(t *testing.T) {
+	result := parseOnly(t, FormatFirefoxCSV, `"url","username","password","httpRealm","formActionOrigin","guid","timeCreated","timeLastUsed","timePasswordChanged"
+"https://accounts.example.org","carol","pw","realm","https://accounts.example.org","{abc}","1700000000123","1710000000000","1705000000000"
+`)
+
+	want := []Record{{Where: "line 2", CredInfo: state.CredInfo{
+		Source: "accounts.example.org", Username: "carol", Password: "pw",
+		URLs:     []string{"https://accounts.example.org"},
+		Created:  time.UnixMilli(1700000000123),
+		Modified: time.UnixMilli(1705000000000),
+		LastUsed: time.UnixMilli(1710000000000),
+	}, Unmapped: []string{"httprealm"}}}
+	if !reflect.DeepEqual(result.Records, want) {
+		t.Errorf("got %+v, want %+v", result.Records, want)
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	if _, err := Parse(FormatChromeCSV, []byte("title,username,password\n")); err == nil {
+		t.Errorf("expected an error for a csv without a url column")
+	}
+	if _, err := Parse("nope", nil); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
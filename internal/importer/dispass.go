@@ -0,0 +1,25 @@
+package importer
+
+import (
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/state"
+)
+
+// FormatDispassEncrypted is read with ParseDispassEncrypted rather than
+// Parse, as it needs the passphrase it was exported with.
+const FormatDispassEncrypted Format = "dispass-encrypted"
+
+// ParseDispassEncrypted reads an archive written by dispass export, which
+// holds entries exactly as they were.
+func ParseDispassEncrypted(data []byte, passphrase string) (Result, error) {
+	creds, err := passio.DecodeArchive(data, passphrase)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, id := range state.SortedIDs(creds) {
+		result.add(Record{Where: "entry " + id, CredInfo: creds[id]})
+	}
+	return result, nil
+}
@@ -0,0 +1,185 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+type Format string
+
+const (
+	FormatBitwardenJSON Format = "bitwarden-json"
+	Format1Password1PUX Format = "1password-1pux"
+	FormatLastPassCSV   Format = "lastpass-csv"
+	FormatKeePassXCCSV  Format = "keepassxc-csv"
+	FormatChromeCSV     Format = "chrome-csv"
+	FormatFirefoxCSV    Format = "firefox-csv"
+)
+
+var parsers = map[Format]func(data []byte) (Result, error){
+	FormatBitwardenJSON: parseBitwarden,
+	Format1Password1PUX: parse1PUX,
+	FormatLastPassCSV:   parseLastPass,
+	FormatKeePassXCCSV:  parseKeePassXC,
+	FormatChromeCSV:     parseChrome,
+	FormatFirefoxCSV:    parseFirefox,
+}
+
+// Formats lists the supported formats, sorted.
+func Formats() []string {
+	formats := []string{string(FormatKDBX), string(FormatPass), string(FormatDispassEncrypted)}
+	for format := range parsers {
+		formats = append(formats, string(format))
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Record is one entry read from an export. Where points back at it, such as
+// a line number, and Unmapped names anything that had nowhere to go.
+type Record struct {
+	Where    string
+	CredInfo state.CredInfo
+	Unmapped []string
+}
+
+// Skip is something in an export that could not be imported at all
+type Skip struct {
+	Where  string
+	Reason string
+}
+
+type Result struct {
+	Records []Record
+	Skipped []Skip
+}
+
+func (r *Result) add(record Record) {
+	ci := record.CredInfo
+	if ci.Password == "" && ci.Username == "" && ci.Notes == "" && ci.TOTP == "" && len(ci.Fields) == 0 {
+		r.skip(record.Where, "nothing to import")
+		return
+	}
+	if ci.Source == "" {
+		ci.Source = sourceFromURLs(ci.URLs)
+	}
+	if ci.Source == "" {
+		ci.Source = ci.Username
+	}
+	if ci.Source == "" {
+		ci.Source = "untitled"
+	}
+	record.CredInfo = ci
+	r.Records = append(r.Records, record)
+}
+
+// Entry is the record as a new entry, keeping the timestamps of the export
+// where it had them.
+func (r Record) Entry(now time.Time) state.CredInfo {
+	ci := state.NewCredInfo(r.CredInfo, now)
+	if !r.CredInfo.Created.IsZero() {
+		ci.Created = r.CredInfo.Created
+	}
+	if !r.CredInfo.Modified.IsZero() {
+		ci.Modified = r.CredInfo.Modified
+		// it can't have been created after it last changed
+		if r.CredInfo.Created.IsZero() {
+			ci.Created = r.CredInfo.Modified
+		}
+	}
+	ci.LastUsed = r.CredInfo.LastUsed
+	return ci
+}
+
+func (r *Result) skip(where, reason string) {
+	r.Skipped = append(r.Skipped, Skip{Where: where, Reason: reason})
+}
+
+// Parse reads an export in the given format.
+func Parse(format Format, data []byte) (Result, error) {
+	if format == FormatKDBX {
+		return Result{}, fmt.Errorf("%v databases need a password, use ParseKDBX", format)
+	}
+	if format == FormatDispassEncrypted {
+		return Result{}, fmt.Errorf("%v archives need a passphrase, use ParseDispassEncrypted", format)
+	}
+	if format == FormatPass {
+		return Result{}, fmt.Errorf("%v stores are directories, use ParsePass", format)
+	}
+	parse, ok := parsers[format]
+	if !ok {
+		return Result{}, fmt.Errorf("unknown format %q, expected one of %v", format, strings.Join(Formats(), ", "))
+	}
+	return parse(data)
+}
+
+// sourceFromURLs names an entry after the host of its first URL.
+func sourceFromURLs(urls []string) string {
+	for _, raw := range urls {
+		if host := hostOf(raw); host != "" {
+			return host
+		}
+	}
+	return ""
+}
+
+func hostOf(raw string) string {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// Duplicate finds an existing entry the imported one is most likely a copy
+// of, one with the same username and either the same source or a URL on
+// the same host.
+func Duplicate(existing map[string]state.CredInfo, ci state.CredInfo) (string, bool) {
+	hosts := make(map[string]bool)
+	for _, raw := range ci.URLs {
+		if host := hostOf(raw); host != "" {
+			hosts[host] = true
+		}
+	}
+
+	// go through ids in order so the same one is reported every time
+	ids := make([]string, 0, len(existing))
+	for id := range existing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		other := existing[id]
+		if other.Username != ci.Username {
+			continue
+		}
+		if strings.EqualFold(other.Source, ci.Source) {
+			return id, true
+		}
+		for _, raw := range other.URLs {
+			if hosts[hostOf(raw)] {
+				return id, true
+			}
+		}
+	}
+	return "", false
+}
+
+// folderTag turns a folder path into a tag, one per level would be too noisy
+// so the whole path is kept together.
+func folderTag(folder string) []string {
+	folder = strings.Trim(strings.ReplaceAll(folder, `\`, "/"), "/ ")
+	if folder == "" {
+		return nil
+	}
+	return []string{folder}
+}
@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dismint/dispass/internal/kdbx"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/totp"
+)
+
+// FormatKDBX is read with ParseKDBX rather than Parse, as it needs a password.
+const FormatKDBX Format = "kdbx"
+
+// keys keepassxc uses for additional urls, KP2A_URL, KP2A_URL_1 and so on
+const kdbxURLPrefix = "KP2A_URL"
+
+// keys of the totp settings, keepassxc keeps an otpauth URI and keepass
+// splits it over several strings
+const (
+	kdbxOTP          = "otp"
+	kdbxOTPSecret    = "TimeOtp-Secret-Base32"
+	kdbxOTPLength    = "TimeOtp-Length"
+	kdbxOTPPeriod    = "TimeOtp-Period"
+	kdbxOTPAlgorithm = "TimeOtp-Algorithm"
+)
+
+// ParseKDBX reads a KeePass database. The group of an entry becomes its first
+// tag, and anything in the recycle bin is skipped.
+func ParseKDBX(data []byte, password string) (Result, error) {
+	db, err := kdbx.Read(data, password)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	var walk func(group kdbx.Group, path []string)
+	walk = func(group kdbx.Group, path []string) {
+		where := "/" + strings.Join(path, "/")
+		for _, entry := range group.Entries {
+			entryWhere := fmt.Sprintf("%v entry %q", where, entry.Get(kdbx.KeyTitle))
+			if db.RecycleBin != (kdbx.UUID{}) && group.UUID == db.RecycleBin {
+				result.skip(entryWhere, "in the recycle bin")
+				continue
+			}
+			result.add(kdbxRecord(entryWhere, entry, strings.Join(path, "/")))
+		}
+		for _, child := range group.Groups {
+			walk(child, append(slices.Clone(path), child.Name))
+		}
+	}
+	// the root group is named after the database, not worth a tag
+	walk(db.Root, nil)
+	return result, nil
+}
+
+func kdbxRecord(where string, entry kdbx.Entry, group string) Record {
+	record := Record{
+		Where: where,
+		CredInfo: state.CredInfo{
+			Source:   entry.Get(kdbx.KeyTitle),
+			Username: entry.Get(kdbx.KeyUserName),
+			Password: entry.Get(kdbx.KeyPassword),
+			Notes:    entry.Get(kdbx.KeyNotes),
+			Tags:     folderTag(group),
+			Created:  entry.Times.Created,
+			Modified: entry.Times.Modified,
+		},
+	}
+	ci := &record.CredInfo
+	if u := entry.Get(kdbx.KeyURL); u != "" {
+		ci.URLs = append(ci.URLs, u)
+	}
+	for _, tag := range entry.Tags {
+		if !slices.Contains(ci.Tags, tag) {
+			ci.Tags = append(ci.Tags, tag)
+		}
+	}
+
+	urlKeys := make([]string, 0)
+	for _, s := range entry.Strings {
+		switch {
+		case s.Key == kdbx.KeyTitle, s.Key == kdbx.KeyUserName, s.Key == kdbx.KeyPassword,
+			s.Key == kdbx.KeyURL, s.Key == kdbx.KeyNotes:
+		case s.Key == kdbxOTP:
+			ci.TOTP = s.Value
+		case strings.HasPrefix(s.Key, "TimeOtp-"):
+			// read all at once below
+		case strings.HasPrefix(s.Key, kdbxURLPrefix):
+			urlKeys = append(urlKeys, s.Key)
+		case s.Value == "":
+		default:
+			fieldType := state.FieldText
+			if s.Protected {
+				fieldType = state.FieldHidden
+			} else if isURL(s.Value) {
+				fieldType = state.FieldURL
+			}
+			ci.Fields = append(ci.Fields, state.CustomField{Name: s.Key, Type: fieldType, Value: s.Value})
+		}
+	}
+	sort.Strings(urlKeys)
+	for _, key := range urlKeys {
+		if u := entry.Get(key); u != "" && !slices.Contains(ci.URLs, u) {
+			ci.URLs = append(ci.URLs, u)
+		}
+	}
+
+	if ci.TOTP == "" && entry.Get(kdbxOTPSecret) != "" {
+		otp, err := keepassOTP(entry, ci.Source, ci.Username)
+		if err != nil {
+			record.Unmapped = append(record.Unmapped, "totp: "+err.Error())
+		} else {
+			ci.TOTP = otp
+		}
+	}
+
+	ci.History = kdbxHistory(entry)
+	if entry.Times.Expires {
+		record.Unmapped = append(record.Unmapped, "expiry date")
+	}
+	for _, name := range entry.Attachments {
+		record.Unmapped = append(record.Unmapped, "attachment "+name)
+	}
+	return record
+}
+
+func isURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// keepassOTP turns the totp settings of keepass into an otpauth URI.
+func keepassOTP(entry kdbx.Entry, issuer, account string) (string, error) {
+	key, err := totp.Parse(entry.Get(kdbxOTPSecret))
+	if err != nil {
+		return "", err
+	}
+	key.Issuer, key.Account = issuer, account
+	if length := entry.Get(kdbxOTPLength); length != "" {
+		if key.Digits, err = strconv.Atoi(length); err != nil || key.Digits < totp.MinDigits || key.Digits > totp.MaxDigits {
+			return "", fmt.Errorf("invalid length %q", length)
+		}
+	}
+	if period := entry.Get(kdbxOTPPeriod); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil || seconds <= 0 {
+			return "", fmt.Errorf("invalid period %q", period)
+		}
+		key.Period = time.Duration(seconds) * time.Second
+	}
+	switch algorithm := entry.Get(kdbxOTPAlgorithm); algorithm {
+	case "", "HMAC-SHA-1":
+	case "HMAC-SHA-256":
+		key.Algorithm = totp.SHA256
+	case "HMAC-SHA-512":
+		key.Algorithm = totp.SHA512
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	// check the URI parses like any other
+	if _, err := totp.Parse(key.URI()); err != nil {
+		return "", err
+	}
+	return key.URI(), nil
+}
+
+// kdbxHistory collects the passwords of previous versions of an entry. A
+// password was replaced when the version after it was saved.
+func kdbxHistory(entry kdbx.Entry) []state.PreviousPassword {
+	versions := slices.Clone(entry.History)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Times.Modified.Before(versions[j].Times.Modified)
+	})
+	versions = append(versions, entry)
+
+	history := make([]state.PreviousPassword, 0)
+	for i := len(versions) - 2; i >= 0; i-- {
+		password := versions[i].Get(kdbx.KeyPassword)
+		next := versions[i+1]
+		if password == "" || password == next.Get(kdbx.KeyPassword) {
+			continue
+		}
+		history = append(history, state.PreviousPassword{
+			Password: password,
+			Replaced: next.Times.Modified,
+		})
+	}
+	if len(history) == 0 {
+		return nil
+	}
+	return history
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dismint/dispass/internal/exporter"
+	"github.com/dismint/dispass/internal/kdbx"
+	"github.com/dismint/dispass/internal/state"
+)
+
+func TestParseKDBXFixture(t *testing.T) {
+	data, err := os.ReadFile("../kdbx/testdata/keepass-argon2d-aes.kdbx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := ParseKDBX(data, "abcdefg12345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := make(map[string]Record)
+	for _, record := range result.Records {
+		sources[record.CredInfo.Source] = record
+	}
+	sample, ok := sources["Sample Entry"]
+	if !ok {
+		t.Fatalf("no Sample Entry among %v", result.Records)
+	}
+	if ci := sample.CredInfo; ci.Username != "User Name" || ci.Password != "Password" ||
+		!reflect.DeepEqual(ci.URLs, []string{"http://keepass.info/"}) || !reflect.DeepEqual(ci.Tags, []string{"General"}) {
+		t.Errorf("unexpected Sample Entry %+v", ci)
+	}
+
+	copied := sources["File test - Copy"]
+	if want := []state.CustomField{{Name: "test", Type: state.FieldHidden, Value: "prova"}}; !reflect.DeepEqual(copied.CredInfo.Fields, want) {
+		t.Errorf("fields: got %+v, want %+v", copied.CredInfo.Fields, want)
+	}
+	if want := []string{"attachment example.txt"}; !reflect.DeepEqual(copied.Unmapped, want) {
+		t.Errorf("unmapped: got %v, want %v", copied.Unmapped, want)
+	}
+}
+
+func TestKDBXRoundTrip(t *testing.T) {
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	ci := state.CredInfo{
+		Source:   "GitHub",
+		Username: "me",
+		Password: "current",
+		TOTP:     "otpauth://totp/GitHub:me?algorithm=SHA1&digits=6&issuer=GitHub&period=30&secret=JBSWY3DPEHPK3PXP",
+		URLs:     []string{"https://github.com", "https://gist.github.com", "https://api.github.com"},
+		Notes:    "notes",
+		Tags:     []string{"Work/Dev", "important"},
+		Fields: []state.CustomField{
+			{Name: "pin", Type: state.FieldHidden, Value: "1234"},
+			{Name: "docs", Type: state.FieldURL, Value: "https://docs.github.com"},
+			{Name: "Title", Type: state.FieldText, Value: "clashes with a standard key"},
+		},
+		History: []state.PreviousPassword{
+			{Password: "second", Replaced: created.Add(48 * time.Hour)},
+			{Password: "first", Replaced: created.Add(24 * time.Hour)},
+		},
+		Created:  created,
+		Modified: created.Add(48 * time.Hour),
+	}
+
+	var buf bytes.Buffer
+	opts := kdbx.Options{KDF: kdbx.KDFArgon2id, Cipher: kdbx.CipherChaCha20, Iterations: 1, Memory: 1 << 20, Parallelism: 1}
+	if err := exporter.KDBX(&buf, map[string]state.CredInfo{"id": ci}, "pw", opts); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ParseKDBX(buf.Bytes(), "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Records) != 1 || len(result.Skipped) != 0 || len(result.Records[0].Unmapped) != 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	want := ci
+	want.Fields[2].Name = "Title (2)"
+	if got := result.Records[0].CredInfo; !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the entry\ngot  %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+type onePasswordExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePasswordItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePasswordItem struct {
+	State     string `json:"state"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+	Overview  struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+		Tags []string `json:"tags"`
+	} `json:"overview"`
+	Details struct {
+		LoginFields []struct {
+			Name        string `json:"name"`
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Fields []struct {
+				Title string                     `json:"title"`
+				ID    string                     `json:"id"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+		PasswordHistory []struct {
+			Value string `json:"value"`
+			Time  int64  `json:"time"`
+		} `json:"passwordHistory"`
+	} `json:"details"`
+}
+
+func parse1PUX(data []byte) (Result, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to open 1pux archive: %w", err)
+	}
+	file, err := archive.Open("export.data")
+	if err != nil {
+		return Result{}, fmt.Errorf("1pux archive has no export.data: %w", err)
+	}
+	defer file.Close()
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to read export.data: %w", err)
+	}
+
+	var export onePasswordExport
+	if err := json.Unmarshal(raw, &export); err != nil {
+		return Result{}, fmt.Errorf("failed to read 1pux export: %w", err)
+	}
+
+	var result Result
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for i, item := range vault.Items {
+				where := fmt.Sprintf("vault %q item %d %q", vault.Attrs.Name, i+1, item.Overview.Title)
+				if item.State == "archived" {
+					result.skip(where, "archived")
+					continue
+				}
+				result.add(onePasswordRecord(where, item))
+			}
+		}
+	}
+	return result, nil
+}
+
+func onePasswordRecord(where string, item onePasswordItem) Record {
+	record := Record{
+		Where: where,
+		CredInfo: state.CredInfo{
+			Source:   item.Overview.Title,
+			Notes:    item.Details.NotesPlain,
+			Password: item.Details.Password,
+			Tags:     item.Overview.Tags,
+			Created:  unixTime(item.CreatedAt),
+			Modified: unixTime(item.UpdatedAt),
+		},
+	}
+	ci := &record.CredInfo
+
+	if item.Overview.URL != "" {
+		ci.URLs = append(ci.URLs, item.Overview.URL)
+	}
+	for _, u := range item.Overview.URLs {
+		if u.URL != "" && u.URL != item.Overview.URL {
+			ci.URLs = append(ci.URLs, u.URL)
+		}
+	}
+
+	for _, field := range item.Details.LoginFields {
+		switch field.Designation {
+		case "username":
+			ci.Username = field.Value
+		case "password":
+			ci.Password = field.Value
+		default:
+			// other form fields are only what the browser filled in
+			if field.Value != "" {
+				record.Unmapped = append(record.Unmapped, "login field "+field.Name)
+			}
+		}
+	}
+
+	for _, section := range item.Details.Sections {
+		for _, field := range section.Fields {
+			name := field.Title
+			if name == "" {
+				name = field.ID
+			}
+			onePasswordField(&record, name, field.Value)
+		}
+	}
+
+	for _, previous := range item.Details.PasswordHistory {
+		ci.History = append(ci.History, state.PreviousPassword{
+			Password: previous.Value,
+			Replaced: unixTime(previous.Time),
+		})
+	}
+
+	return record
+}
+
+// onePasswordField maps a section field, whose value is an object with a
+// single key naming its kind.
+func onePasswordField(record *Record, name string, value map[string]json.RawMessage) {
+	ci := &record.CredInfo
+	for kind, raw := range value {
+		var text string
+		switch kind {
+		case "email":
+			var email struct {
+				Address string `json:"email_address"`
+			}
+			if json.Unmarshal(raw, &email) != nil {
+				record.Unmapped = append(record.Unmapped, "field "+name)
+				continue
+			}
+			text = email.Address
+		default:
+			if json.Unmarshal(raw, &text) != nil {
+				record.Unmapped = append(record.Unmapped, "field "+name)
+				continue
+			}
+		}
+		if text == "" {
+			continue
+		}
+
+		switch kind {
+		case "totp":
+			ci.TOTP = text
+		case "concealed":
+			ci.Fields = append(ci.Fields, state.CustomField{Name: name, Type: state.FieldHidden, Value: text})
+		case "url":
+			ci.Fields = append(ci.Fields, state.CustomField{Name: name, Type: state.FieldURL, Value: text})
+		case "string", "email", "phone":
+			ci.Fields = append(ci.Fields, state.CustomField{Name: name, Type: state.FieldText, Value: text})
+		default:
+			record.Unmapped = append(record.Unmapped, "field "+name)
+		}
+	}
+}
+
+func unixTime(seconds int64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
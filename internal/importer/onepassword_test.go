@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+// write1PUX zips export.data the way 1Password lays out an export.
+func write1PUX(t *testing.T, exportData string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"export.attributes": `{"version": 3}`,
+		"export.data":       exportData,
+	} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParse1PUX(t *testing.T) {
+	data := write1PUX(t, `{"accounts": [{"vaults": [{
+  "attrs": {"name": "Personal"},
+  "items": [
+    {
+      "state": "active", "createdAt": 1700000000, "updatedAt": 1705000000,
+      "overview": {
+        "title": "GitHub", "url": "https://github.com",
+        "urls": [{"url": "https://github.com"}, {"url": "https://gist.github.com"}],
+        "tags": ["work"]
+      },
+      "details": {
+        "loginFields": [
+          {"name": "login", "value": "alice", "designation": "username"},
+          {"name": "pass", "value": "hunter2", "designation": "password"},
+          {"name": "remember", "value": "✓", "designation": ""}
+        ],
+        "notesPlain": "main account",
+        "sections": [{"fields": [
+          {"title": "one-time password", "id": "otp", "value": {"totp": "otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP"}},
+          {"title": "", "id": "recovery", "value": {"concealed": "abcd-efgh"}},
+          {"title": "backup email", "id": "e", "value": {"email": {"email_address": "a@example.com"}}},
+          {"title": "console", "id": "u", "value": {"url": "https://console.example.com"}},
+          {"title": "signed up", "id": "d", "value": {"date": 1600000000}},
+          {"title": "empty", "id": "x", "value": {"string": ""}}
+        ]}],
+        "passwordHistory": [{"value": "hunter1", "time": 1690000000}]
+      }
+    },
+    {
+      "state": "archived",
+      "overview": {"title": "Old"},
+      "details": {"password": "gone"}
+    }
+  ]
+}]}]}`)
+
+	result, err := Parse(Format1Password1PUX, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Record{{
+		Where: `vault "Personal" item 1 "GitHub"`,
+		CredInfo: state.CredInfo{
+			Source: "GitHub", Username: "alice", Password: "hunter2",
+			TOTP:  "otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP",
+			URLs:  []string{"https://github.com", "https://gist.github.com"},
+			Notes: "main account", Tags: []string{"work"},
+			Fields: []state.CustomField{
+				{Name: "recovery", Type: state.FieldHidden, Value: "abcd-efgh"},
+				{Name: "backup email", Type: state.FieldText, Value: "a@example.com"},
+				{Name: "console", Type: state.FieldURL, Value: "https://console.example.com"},
+			},
+			History:  []state.PreviousPassword{{Password: "hunter1", Replaced: time.Unix(1690000000, 0)}},
+			Created:  time.Unix(1700000000, 0),
+			Modified: time.Unix(1705000000, 0),
+		},
+		Unmapped: []string{"login field remember", "field signed up"},
+	}}
+	if !reflect.DeepEqual(result.Records, want) {
+		t.Errorf("got %+v, want %+v", result.Records, want)
+	}
+	if want := []Skip{{Where: `vault "Personal" item 2 "Old"`, Reason: "archived"}}; !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("skipped: got %+v, want %+v", result.Skipped, want)
+	}
+}
+
+func TestParse1PUXErrors(t *testing.T) {
+	if _, err := Parse(Format1Password1PUX, []byte("not a zip")); err == nil {
+		t.Errorf("expected an error for something that isn't a zip")
+	}
+	var buf bytes.Buffer
+	zip.NewWriter(&buf).Close()
+	if _, err := Parse(Format1Password1PUX, buf.Bytes()); err == nil {
+		t.Errorf("expected an error for an archive without export.data")
+	}
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dismint/dispass/internal/state"
+)
+
+// FormatPass is read with ParsePass rather than Parse, as it is a directory
+// of encrypted files.
+const FormatPass Format = "pass"
+
+// keys pass extensions and browser plugins use for the username
+var passUserKeys = []string{"login", "user", "username"}
+
+// ParsePass reads a password store. Each .gpg file is an entry named after
+// its path in the store, decrypted with decrypt.
+func ParsePass(dir string, decrypt func(path string) ([]byte, error)) (Result, error) {
+	if info, err := os.Stat(dir); err != nil {
+		return Result{}, err
+	} else if !info.IsDir() {
+		return Result{}, fmt.Errorf("%v is not a directory", dir)
+	}
+
+	var result Result
+	err := walkPass(dir, func(path, name string, d fs.DirEntry) error {
+		plaintext, err := decrypt(path)
+		if err != nil {
+			result.skip(name, err.Error())
+			return nil
+		}
+		record := passRecord(name, string(plaintext))
+		// the last change is all a store knows about an entry's history
+		if info, err := d.Info(); err == nil {
+			record.CredInfo.Created = info.ModTime()
+			record.CredInfo.Modified = info.ModTime()
+		}
+		result.add(record)
+		return nil
+	})
+	return result, err
+}
+
+// FirstPassFile finds an encrypted file in the store at dir, so gpg-agent can
+// be given the chance to ask for the passphrase before the store is read.
+// It is empty if the store holds none.
+func FirstPassFile(dir string) (string, error) {
+	first := ""
+	err := walkPass(dir, func(path, _ string, _ fs.DirEntry) error {
+		first = path
+		return fs.SkipAll
+	})
+	return first, err
+}
+
+// walkPass calls fn with every .gpg file in the store at dir and its name in
+// the store.
+func walkPass(dir string, fn func(path, name string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// .git and .extensions hold nothing to import
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".gpg") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(strings.TrimSuffix(rel, ".gpg")), d)
+	})
+}
+
+// passRecord follows the pass convention of the password on the first line.
+// key: value lines naming the username or a URL are picked out, as are
+// otpauth URIs from pass-otp, and everything else is kept as notes.
+func passRecord(name, plaintext string) Record {
+	lines := strings.Split(strings.ReplaceAll(plaintext, "\r\n", "\n"), "\n")
+	ci := state.CredInfo{Source: name, Password: lines[0]}
+
+	notes := make([]string, 0)
+	for _, line := range lines[1:] {
+		key, value, found := strings.Cut(line, ":")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch {
+		case found && ci.Username == "" && slices.Contains(passUserKeys, key):
+			ci.Username = value
+		case found && (key == "url" || key == "website") && value != "":
+			ci.URLs = append(ci.URLs, value)
+		case ci.TOTP == "" && strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "otpauth://"):
+			ci.TOTP = strings.TrimSpace(line)
+		default:
+			notes = append(notes, line)
+		}
+	}
+	ci.Notes = strings.TrimRight(strings.Join(notes, "\n"), "\n")
+
+	return Record{Where: name, CredInfo: ci}
+}
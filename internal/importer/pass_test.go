@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dismint/dispass/internal/exporter"
+	"github.com/dismint/dispass/internal/state"
+)
+
+func TestParsePassRecord(t *testing.T) {
+	record := passRecord("web/example.com", "hunter2\r\nLogin: alice\nuser: not me\nurl: https://example.com\n\nsome note\n")
+	want := state.CredInfo{
+		Source:   "web/example.com",
+		Username: "alice",
+		Password: "hunter2",
+		URLs:     []string{"https://example.com"},
+		Notes:    "user: not me\n\nsome note",
+	}
+	if !reflect.DeepEqual(record.CredInfo, want) {
+		t.Errorf("got %+v, want %+v", record.CredInfo, want)
+	}
+}
+
+// the round trip skips gpg, which is only a matter of running it
+func TestPassRoundTrip(t *testing.T) {
+	creds := map[string]state.CredInfo{
+		"a": {
+			Source:   "Work/GitHub",
+			Username: "me",
+			Password: "current",
+			TOTP:     "otpauth://totp/GitHub:me?algorithm=SHA1&digits=6&issuer=GitHub&period=30&secret=JBSWY3DPEHPK3PXP",
+			URLs:     []string{"https://github.com", "https://gist.github.com"},
+			Notes:    "first line\nsecond line",
+		},
+		"b": {Source: "Work/GitHub", Password: "same source"},
+		"c": {Source: "../outside", Password: "kept inside"},
+	}
+	identity := func(plaintext []byte, recipients []string) ([]byte, error) { return plaintext, nil }
+
+	dir := t.TempDir()
+	if err := exporter.Pass(dir, creds, []string{"key"}, identity, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Pass(dir, creds, []string{"key"}, identity, false); err == nil {
+		t.Errorf("overwrote the store without force")
+	}
+	if keys, err := exporter.ReadGPGID(dir); err != nil || !reflect.DeepEqual(keys, []string{"key"}) {
+		t.Errorf("gpg-id: got %v, %v", keys, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.gpg"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ParsePass(dir, func(path string) ([]byte, error) {
+		if filepath.Base(path) == "broken.gpg" {
+			return nil, errors.New("no secret key")
+		}
+		return os.ReadFile(path)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Skip{{Where: "broken", Reason: "no secret key"}}; !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("skipped: got %+v, want %+v", result.Skipped, want)
+	}
+
+	got := make(map[string]state.CredInfo)
+	for _, record := range result.Records {
+		// times come from the files, not the entries
+		ci := record.CredInfo
+		ci.Created, ci.Modified = time.Time{}, time.Time{}
+		got[ci.Source] = ci
+	}
+	github := creds["a"]
+	if !reflect.DeepEqual(got["Work/GitHub"], github) {
+		t.Errorf("got %+v, want %+v", got["Work/GitHub"], github)
+	}
+	if got["Work/GitHub (2)"].Password != "same source" || got["outside"].Password != "kept inside" {
+		t.Errorf("unexpected sources %v", got)
+	}
+}
@@ -0,0 +1,108 @@
+package importscreen
+
+import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/dismint/dispass/internal/importer"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+type KeyMap struct {
+	Quit   key.Binding
+	Format key.Binding
+	Enter  key.Binding
+	Back   key.Binding
+}
+
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Quit, k.Format, k.Enter, k.Back}
+}
+
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Quit, k.Format},
+		{k.Enter, k.Back},
+	}
+}
+
+var keyMap = KeyMap{
+	Quit: key.NewBinding(
+		key.WithKeys("ctrl+c"),
+		key.WithHelp("ctrl+c", "quit"),
+	),
+	Format: key.NewBinding(
+		key.WithKeys("tab", "shift+tab"),
+		key.WithHelp("tab", "format"),
+	),
+	Enter: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("↵", "preview"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+// preview is what importing the file would do, the entries are only added
+// once it is confirmed.
+type preview struct {
+	pending    map[string]state.CredInfo
+	order      []string
+	duplicates int
+	skipped    int
+	partial    int
+}
+
+// unlockedMsg is sent once gpg-agent has had the chance to ask for the
+// passphrase of a password store.
+type unlockedMsg struct{}
+
+// loadedMsg is the parsed file, unreadable is set when it couldn't be read at
+// all.
+type loadedMsg struct {
+	result     importer.Result
+	err        error
+	unreadable bool
+}
+
+type Model struct {
+	keyMap    KeyMap
+	helpModel help.Model
+
+	formats   []string
+	formatLoc int
+
+	pathInput     textinput.Model
+	passwordInput textinput.Model
+
+	loading bool
+	preview *preview
+}
+
+func Initial() Model {
+	passwordInput := uconst.NewTextInput("Password » ")
+	passwordInput.CharLimit = -1
+	passwordInput.EchoMode = textinput.EchoPassword
+	passwordInput.EchoCharacter = uconst.PasswordChar
+
+	helpModel := help.New()
+	helpModel.Styles = uconst.HelpStyles
+	helpModel.ShowAll = true
+
+	return Model{
+		keyMap:    keyMap,
+		helpModel: helpModel,
+
+		formats: importer.Formats(),
+		// formatLoc
+
+		pathInput:     uconst.NewTextInput("File     » "),
+		passwordInput: passwordInput,
+
+		loading: false,
+		preview: nil,
+	}
+}
@@ -0,0 +1,235 @@
+package importscreen
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dismint/dispass/internal/fuzzy"
+	"github.com/dismint/dispass/internal/gpg"
+	"github.com/dismint/dispass/internal/importer"
+	"github.com/dismint/dispass/internal/kdbx"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+	"github.com/google/uuid"
+)
+
+func (m *Model) format() importer.Format {
+	return importer.Format(m.formats[m.formatLoc])
+}
+
+// needsPassword is true for the formats that are encrypted with a password
+// rather than gpg.
+func (m *Model) needsPassword() bool {
+	return m.format() == importer.FormatKDBX || m.format() == importer.FormatDispassEncrypted
+}
+
+func (m *Model) setPreview(p *preview) {
+	m.preview = p
+	if p == nil {
+		m.keyMap.Enter.SetHelp("↵", "preview")
+	} else {
+		m.keyMap.Enter.SetHelp("↵", "import")
+	}
+}
+
+func (m *Model) reset() tea.Cmd {
+	m.setPreview(nil)
+	// whatever is still being read is thrown away when it arrives
+	m.loading = false
+	m.passwordInput.SetValue("")
+	m.passwordInput.Blur()
+	return m.pathInput.Focus()
+}
+
+func (m *Model) transitionState(sm *state.Model) {
+	sm.Screen = state.InteractScreen
+	m.reset()
+	m.pathInput.Blur()
+}
+
+// load reads the file off the Update loop. gpg is first given the terminal
+// to decrypt one file of a password store, so gpg-agent can ask for the
+// passphrase before the rest is decrypted in the background.
+func (m *Model) load() tea.Cmd {
+	m.loading = true
+	if m.format() == importer.FormatPass {
+		first, err := importer.FirstPassFile(uconst.ExpandHome(m.pathInput.Value()))
+		if err == nil && first != "" {
+			return tea.ExecProcess(gpg.DecryptCommand(first), func(error) tea.Msg {
+				// a failure shows up again, per file, once the store is read
+				return unlockedMsg{}
+			})
+		}
+	}
+	return m.parse()
+}
+
+func (m *Model) parse() tea.Cmd {
+	format := m.format()
+	path := uconst.ExpandHome(m.pathInput.Value())
+	password := m.passwordInput.Value()
+	return func() tea.Msg {
+		var data []byte
+		var err error
+		if format != importer.FormatPass {
+			if data, err = os.ReadFile(path); err != nil {
+				return loadedMsg{err: err, unreadable: true}
+			}
+		}
+
+		var result importer.Result
+		switch format {
+		case importer.FormatKDBX:
+			result, err = importer.ParseKDBX(data, password)
+		case importer.FormatDispassEncrypted:
+			result, err = importer.ParseDispassEncrypted(data, password)
+		case importer.FormatPass:
+			result, err = importer.ParsePass(path, gpg.Decrypt)
+		default:
+			result, err = importer.Parse(format, data)
+		}
+		return loadedMsg{result: result, err: err}
+	}
+}
+
+// loaded works out which entries would be new, the same way the import
+// command does.
+func (m *Model) loaded(msg loadedMsg, sm *state.Model) tea.Cmd {
+	m.loading = false
+	if msg.unreadable {
+		return state.NotificationMsg(
+			fmt.Sprintf("Could not read %v", m.pathInput.Value()),
+			state.MessageLevelError,
+		)
+	}
+	if errors.Is(msg.err, kdbx.ErrIncorrectPassword) || errors.Is(msg.err, passio.ErrIncorrectPassword) {
+		m.passwordInput.SetValue("")
+		return state.NotificationMsg("Incorrect Password", state.MessageLevelError)
+	} else if msg.err != nil {
+		return state.NotificationMsg(
+			fmt.Sprintf("Could not import: %v", msg.err),
+			state.MessageLevelError,
+		)
+	}
+
+	p := &preview{
+		pending: make(map[string]state.CredInfo),
+		skipped: len(msg.result.Skipped),
+	}
+	// earlier records count as existing so a file can't duplicate itself
+	existing := maps.Clone(sm.KeyToCredInfo)
+	now := time.Now()
+	for _, record := range msg.result.Records {
+		if _, ok := importer.Duplicate(existing, record.CredInfo); ok {
+			p.duplicates++
+			continue
+		}
+		id := uuid.NewString()
+		ci := record.Entry(now)
+		existing[id] = ci
+		p.pending[id] = ci
+		p.order = append(p.order, id)
+		if len(record.Unmapped) > 0 {
+			p.partial++
+		}
+	}
+	m.setPreview(p)
+	m.pathInput.Blur()
+	m.passwordInput.Blur()
+	return nil
+}
+
+func (m *Model) commit(sm *state.Model) tea.Cmd {
+	if len(m.preview.order) == 0 {
+		return tea.Batch(
+			state.NotificationMsg("Nothing new to import", state.MessageLevelNotif),
+			m.reset(),
+		)
+	}
+
+	before := sm.Snapshot(m.preview.order...)
+	for _, id := range m.preview.order {
+		credInfo := m.preview.pending[id]
+		sm.KeyToCredInfo[id] = credInfo
+		fuzzy.UpdateFuzzy(sm, id, credInfo)
+	}
+	count := len(m.preview.order)
+	sm.Record(fmt.Sprintf("Import %d entries", count), before)
+	passio.WriteStateCreds(sm)
+	m.transitionState(sm)
+
+	return tea.Batch(
+		state.NotificationMsg(
+			fmt.Sprintf("Imported %d entries", count),
+			state.MessageLevelSuccess,
+		),
+		func() tea.Msg { return state.CredsReloadedMsg{} },
+	)
+}
+
+func (m *Model) Update(msg tea.Msg, sm *state.Model) tea.Cmd {
+	cmds := make([]tea.Cmd, 0)
+
+	if sm.Dirty {
+		return m.reset()
+	}
+
+	if m.preview == nil && !m.loading {
+		var cmd tea.Cmd
+		m.pathInput, cmd = m.pathInput.Update(msg)
+		cmds = append(cmds, cmd)
+		m.passwordInput, cmd = m.passwordInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	switch msg := msg.(type) {
+	case unlockedMsg:
+		if m.loading {
+			cmds = append(cmds, m.parse())
+		}
+	case loadedMsg:
+		if m.loading {
+			cmds = append(cmds, m.loaded(msg, sm))
+		}
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keyMap.Quit):
+			sm.Quitting = true
+			cmds = append(cmds, tea.Quit)
+		case key.Matches(msg, keyMap.Back):
+			if m.preview != nil {
+				cmds = append(cmds, m.reset())
+			} else {
+				m.transitionState(sm)
+			}
+		case m.preview != nil:
+			if key.Matches(msg, keyMap.Enter) {
+				cmds = append(cmds, m.commit(sm))
+			}
+		case key.Matches(msg, keyMap.Format):
+			step := 1
+			if msg.String() == "shift+tab" {
+				step = len(m.formats) - 1
+			}
+			m.formatLoc = (m.formatLoc + step) % len(m.formats)
+			cmds = append(cmds, m.reset())
+		case key.Matches(msg, keyMap.Enter):
+			switch {
+			case m.loading, m.pathInput.Value() == "":
+			case m.needsPassword() && m.pathInput.Focused():
+				m.pathInput.Blur()
+				cmds = append(cmds, m.passwordInput.Focus())
+			default:
+				cmds = append(cmds, m.load())
+			}
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
@@ -0,0 +1,50 @@
+package importscreen
+
+import (
+	"fmt"
+
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+// previewRows is how many of the new entries are listed by name
+const previewRows = 8
+
+func (m *Model) previewView() string {
+	p := m.preview
+	view := uconst.TextStyle.Render(fmt.Sprintf(
+		"%d new, %d duplicates, %d skipped, %d partial",
+		len(p.order), p.duplicates, p.skipped, p.partial,
+	)) + "\n\n"
+	for loc, id := range p.order {
+		if loc == previewRows {
+			view += uconst.TextStyle.Render(fmt.Sprintf("  … and %d more", len(p.order)-previewRows)) + "\n"
+			break
+		}
+		ci := p.pending[id]
+		view += fmt.Sprintf("%v %v %v\n",
+			uconst.SymbolStyle.Render("+"),
+			uconst.TruncAndPadListElem(ci.Source),
+			uconst.TextStyle.Render(ci.Username),
+		)
+	}
+	return view
+}
+
+func (m *Model) View() string {
+	view := fmt.Sprintf("%v\n\n%v%v\n%v\n",
+		m.helpModel.View(m.keyMap),
+		uconst.SymbolStyle.Render("Format   » "),
+		uconst.TextStyle.Render(string(m.format())),
+		m.pathInput.View(),
+	)
+	if m.needsPassword() {
+		view += fmt.Sprintf("%v\n", m.passwordInput.View())
+	}
+	if m.loading {
+		view += "\n" + uconst.TextStyle.Render("Reading…") + "\n"
+	}
+	if m.preview != nil {
+		view += "\n" + m.previewView()
+	}
+	return uconst.ViewStyle.Render(view)
+}
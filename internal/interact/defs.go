@@ -1,9 +1,12 @@
 package interact
 
 import (
+	"time"
+
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/paginator"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/dismint/dispass/internal/state"
 	"github.com/dismint/dispass/internal/uconst"
@@ -19,17 +22,39 @@ type NavKeyMap struct {
 	Clear        key.Binding
 	Nav          key.Binding
 	Copy         key.Binding
+	CopyUser     key.Binding
 	Edit         key.Binding
 	New          key.Binding
 	Del          key.Binding
+	Undo         key.Binding
+	Redo         key.Binding
+	Trash        key.Binding
+	CopyOTP      key.Binding
+	History      key.Binding
 	ChangeMaster key.Binding
+	Backups      key.Binding
+	Import       key.Binding
+	Audit        key.Binding
+	Order        key.Binding
+}
+type HistoryKeyMap struct {
+	Quit    key.Binding
+	Back    key.Binding
+	Nav     key.Binding
+	Copy    key.Binding
+	Restore key.Binding
 }
 type ViewportKeyMap struct {
-	Quit key.Binding
-	Back key.Binding
-	Save key.Binding
-	Next key.Binding
-	Prev key.Binding
+	Quit        key.Binding
+	Back        key.Binding
+	Save        key.Binding
+	Newline     key.Binding
+	Next        key.Binding
+	Prev        key.Binding
+	Generate    key.Binding
+	AddField    key.Binding
+	RemoveField key.Binding
+	FieldType   key.Binding
 }
 
 func (k SearchKeyMap) ShortHelp() []key.Binding {
@@ -42,14 +67,39 @@ func (k NavKeyMap) ShortHelp() []key.Binding {
 		k.Clear,
 		k.Nav,
 		k.Copy,
+		k.CopyUser,
 		k.Edit,
 		k.New,
 		k.Del,
+		k.Undo,
+		k.Redo,
+		k.Trash,
+		k.CopyOTP,
+		k.History,
 		k.ChangeMaster,
+		k.Backups,
+		k.Import,
+		k.Audit,
+		k.Order,
 	}
 }
 func (k ViewportKeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Quit, k.Back, k.Save, k.Next, k.Prev}
+	return []key.Binding{
+		k.Quit,
+		k.Back,
+		k.Save,
+		k.Newline,
+		k.Next,
+		k.Prev,
+		k.Generate,
+		k.AddField,
+		k.RemoveField,
+		k.FieldType,
+	}
+}
+
+func (k HistoryKeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Quit, k.Back, k.Nav, k.Copy, k.Restore}
 }
 
 func (k SearchKeyMap) FullHelp() [][]key.Binding {
@@ -59,15 +109,22 @@ func (k SearchKeyMap) FullHelp() [][]key.Binding {
 }
 func (k NavKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Quit, k.Search, k.Clear},
-		{k.Nav, k.Copy, k.Edit},
-		{k.New, k.Del, k.ChangeMaster},
+		{k.Quit, k.Search, k.Clear, k.Nav, k.Order, k.Undo, k.Redo},
+		{k.Copy, k.Edit, k.New, k.Del, k.Trash, k.Audit},
+		{k.CopyUser, k.CopyOTP, k.History, k.ChangeMaster, k.Backups, k.Import},
 	}
 }
 func (k ViewportKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Quit, k.Back, k.Save},
-		{k.Next, k.Prev},
+		{k.Quit, k.Back, k.Save, k.Newline, k.Generate},
+		{k.Next, k.Prev, k.AddField, k.RemoveField, k.FieldType},
+	}
+}
+
+func (k HistoryKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Quit, k.Back, k.Nav},
+		{k.Copy, k.Restore},
 	}
 }
 
@@ -102,6 +159,10 @@ var navKeyMap = NavKeyMap{
 		key.WithKeys("enter"),
 		key.WithHelp("↵", "copy"),
 	),
+	CopyUser: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "copy user"),
+	),
 	Edit: key.NewBinding(
 		key.WithKeys("e"),
 		key.WithHelp("e", "edit"),
@@ -114,10 +175,46 @@ var navKeyMap = NavKeyMap{
 		key.WithKeys("d"),
 		key.WithHelp("d", "delete"),
 	),
+	Undo: key.NewBinding(
+		key.WithKeys("u"),
+		key.WithHelp("u", "undo"),
+	),
+	Redo: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "redo"),
+	),
+	Trash: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "trash"),
+	),
+	CopyOTP: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "copy otp"),
+	),
+	History: key.NewBinding(
+		key.WithKeys("H"),
+		key.WithHelp("H", "history"),
+	),
 	ChangeMaster: key.NewBinding(
 		key.WithKeys("p"),
 		key.WithHelp("p", "change master"),
 	),
+	Backups: key.NewBinding(
+		key.WithKeys("b"),
+		key.WithHelp("b", "backups"),
+	),
+	Import: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "import"),
+	),
+	Audit: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "audit"),
+	),
+	Order: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "sort"),
+	),
 }
 var viewportKeyMap = ViewportKeyMap{
 	Quit: key.NewBinding(
@@ -137,9 +234,110 @@ var viewportKeyMap = ViewportKeyMap{
 		key.WithHelp("↓ tab", "next"),
 	),
 	Prev: key.NewBinding(
-		key.WithKeys("up"),
+		key.WithKeys("up", "shift+tab"),
 		key.WithHelp("↑", "prev"),
 	),
+	Newline: key.NewBinding(
+		key.WithKeys("alt+enter", "ctrl+j"),
+		key.WithHelp("alt+↵", "newline"),
+	),
+	Generate: key.NewBinding(
+		key.WithKeys("ctrl+g"),
+		key.WithHelp("ctrl+g", "generate"),
+	),
+	AddField: key.NewBinding(
+		key.WithKeys("ctrl+o"),
+		key.WithHelp("ctrl+o", "add field"),
+	),
+	RemoveField: key.NewBinding(
+		key.WithKeys("ctrl+x"),
+		key.WithHelp("ctrl+x", "remove field"),
+	),
+	FieldType: key.NewBinding(
+		key.WithKeys("ctrl+t"),
+		key.WithHelp("ctrl+t", "field type"),
+	),
+}
+
+// focus positions in the viewport form, each custom field takes two after
+// the fixed ones, its name then its value
+const (
+	focusSource = iota
+	focusUsername
+	focusPassword
+	focusTOTP
+	focusURLs
+	focusTags
+	focusNotes
+	focusFields
+)
+
+// how long the notification offering to undo a delete stays up
+const undoNotificationDuration = 5 * time.Second
+
+// lines of the viewport form shown at once, the rest scrolls
+const (
+	viewportHeight      = 9
+	viewportNotesHeight = 3
+)
+
+type viewportField struct {
+	nameInput  textinput.Model
+	valueInput textinput.Model
+	fieldType  state.FieldType
+}
+
+func newViewportField(field state.CustomField) viewportField {
+	nameInput := uconst.NewTextInput("")
+	nameInput.Width = 7
+	nameInput.Placeholder = "name"
+	nameInput.SetValue(field.Name)
+	nameInput.CursorEnd()
+	valueInput := uconst.NewTextInput("")
+	valueInput.SetValue(field.Value)
+	valueInput.CursorEnd()
+
+	viewportField := viewportField{nameInput: nameInput, valueInput: valueInput}
+	viewportField.setType(field.Type)
+	return viewportField
+}
+
+func (f *viewportField) setType(fieldType state.FieldType) {
+	f.fieldType = fieldType
+	f.nameInput.Prompt = fieldTypeSymbols[fieldType] + " "
+	f.valueInput.EchoMode = textinput.EchoNormal
+	if fieldType == state.FieldHidden {
+		f.valueInput.EchoMode = textinput.EchoPassword
+		f.valueInput.EchoCharacter = uconst.PasswordChar
+	}
+}
+
+var fieldTypeSymbols = map[state.FieldType]string{
+	state.FieldText:   "≡",
+	state.FieldHidden: string(uconst.PasswordChar),
+	state.FieldURL:    "↗",
+}
+var historyKeyMap = HistoryKeyMap{
+	Quit: key.NewBinding(
+		key.WithKeys("ctrl+c"),
+		key.WithHelp("ctrl+c", "quit"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+	Nav: key.NewBinding(
+		key.WithKeys("up", "down", "k", "j"),
+		key.WithHelp("↑↓", "nav"),
+	),
+	Copy: key.NewBinding(
+		key.WithKeys("enter"),
+		key.WithHelp("↵", "copy"),
+	),
+	Restore: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "restore"),
+	),
 }
 
 type Mode int
@@ -148,6 +346,7 @@ const (
 	ModeSearch Mode = iota
 	ModeNav
 	ModeViewport
+	ModeHistory
 )
 
 type Model struct {
@@ -159,13 +358,35 @@ type Model struct {
 	viewportSourceInput   textinput.Model
 	viewportUsernameInput textinput.Model
 	viewportPasswordInput textinput.Model
+	viewportTOTPInput     textinput.Model
+	viewportURLsInput     textinput.Model
+	viewportTagsInput     textinput.Model
+	viewportNotesInput    textarea.Model
+	viewportFields        []viewportField
+	viewportFocus         int
 	viewportUUID          string
 
-	lastQuery       string
-	keyInput        textinput.Model
-	resultPaginator paginator.Model
-	resultLocOnPage int
-	topIDs          []string
+	historyLoc int
+
+	lastQuery string
+	// a query that doesn't parse keeps the results of the last one that did
+	lastQueryInvalid bool
+	keyInput         textinput.Model
+	resultPaginator  paginator.Model
+	resultLocOnPage  int
+	topIDs           []string
+	// byFrecency ranks entries used often and lately higher
+	byFrecency bool
+
+	// the otp countdown ticks while a code is shown, ticks that arrive on
+	// another screen are lost so a stale one gets restarted
+	otpTickAt     time.Time
+	otpGeneration int
+
+	// where to return to after being locked
+	restoring    bool
+	restoreQuery string
+	restoreID    string
 }
 
 func Initial() Model {
@@ -177,6 +398,18 @@ func Initial() Model {
 	viewportPasswordInput := uconst.NewTextInput("Password  ")
 	viewportPasswordInput.EchoMode = textinput.EchoPassword
 	viewportPasswordInput.EchoCharacter = uconst.PasswordChar
+	viewportTOTPInput := uconst.NewTextInput("TOTP      ")
+	viewportTOTPInput.Placeholder = "secret or otpauth:// URI"
+	viewportTOTPInput.EchoMode = textinput.EchoPassword
+	viewportTOTPInput.EchoCharacter = uconst.PasswordChar
+	viewportURLsInput := uconst.NewTextInput("URLs      ")
+	viewportURLsInput.Placeholder = "space separated"
+	viewportTagsInput := uconst.NewTextInput("Tags      ")
+	viewportTagsInput.Placeholder = "comma separated"
+	viewportNotesInput := uconst.NewTextArea("Notes     ", viewportNotesHeight)
+	viewportNotesInput.KeyMap.InsertNewline = viewportKeyMap.Newline
+	viewportNotesInput.KeyMap.LineNext.SetKeys("ctrl+n")
+	viewportNotesInput.KeyMap.LinePrevious.SetKeys("ctrl+p")
 
 	resultPaginator := paginator.New()
 	resultPaginator.Type = paginator.Dots
@@ -197,13 +430,28 @@ func Initial() Model {
 		viewportSourceInput:   viewportSourceInput,
 		viewportUsernameInput: viewportUsernameInput,
 		viewportPasswordInput: viewportPasswordInput,
+		viewportTOTPInput:     viewportTOTPInput,
+		viewportURLsInput:     viewportURLsInput,
+		viewportTagsInput:     viewportTagsInput,
+		viewportNotesInput:    viewportNotesInput,
+		// viewportFields
+		// viewportFocus
 		// viewportUUID
 
+		// historyLoc
+
 		// lastQuery
+		// lastQueryInvalid
 		keyInput:        keyInput,
 		resultPaginator: resultPaginator,
 		// resultLocOnPage
-		topIDs: make([]string, 0),
+		topIDs:     make([]string, 0),
+		byFrecency: uconst.SearchFrecency,
+		// otpTickAt
+		// otpGeneration
+		// restoring
+		// restoreQuery
+		// restoreID
 	}
 }
 
@@ -1,16 +1,20 @@
 package interact
 
 import (
+	"fmt"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
 	"github.com/dismint/dispass/internal/fuzzy"
+	"github.com/dismint/dispass/internal/passgen"
 	"github.com/dismint/dispass/internal/passio"
 	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/totp"
 	"github.com/google/uuid"
 )
 
@@ -18,14 +22,159 @@ func (m *Model) setViewportCredInfo(credInfo state.CredInfo, blur bool) {
 	m.viewportSourceInput.SetValue(credInfo.Source)
 	m.viewportUsernameInput.SetValue(credInfo.Username)
 	m.viewportPasswordInput.SetValue(credInfo.Password)
-	m.viewportSourceInput.CursorEnd()
-	m.viewportUsernameInput.CursorEnd()
-	m.viewportPasswordInput.CursorEnd()
+	m.viewportTOTPInput.SetValue(credInfo.TOTP)
+	m.viewportURLsInput.SetValue(strings.Join(credInfo.URLs, " "))
+	m.viewportTagsInput.SetValue(strings.Join(credInfo.Tags, ", "))
+	m.viewportNotesInput.SetValue(credInfo.Notes)
+	m.viewportFields = make([]viewportField, 0, len(credInfo.Fields))
+	for _, field := range credInfo.Fields {
+		m.viewportFields = append(m.viewportFields, newViewportField(field))
+	}
+	for _, ti := range m.viewportInputs() {
+		ti.CursorStart()
+	}
 	if blur {
-		m.viewportSourceInput.Blur()
-		m.viewportUsernameInput.Blur()
-		m.viewportPasswordInput.Blur()
+		m.blurViewport()
+	}
+}
+
+// viewportCredInfo reads the form back, dropping anything left blank.
+func (m *Model) viewportCredInfo() state.CredInfo {
+	credInfo := state.CredInfo{
+		Source:   m.viewportSourceInput.Value(),
+		Username: m.viewportUsernameInput.Value(),
+		Password: m.viewportPasswordInput.Value(),
+		TOTP:     strings.TrimSpace(m.viewportTOTPInput.Value()),
+		URLs:     strings.Fields(m.viewportURLsInput.Value()),
+		Notes:    strings.TrimRight(m.viewportNotesInput.Value(), "\n"),
+	}
+	for _, tag := range strings.Split(m.viewportTagsInput.Value(), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(credInfo.Tags, tag) {
+			credInfo.Tags = append(credInfo.Tags, tag)
+		}
+	}
+	for _, field := range m.viewportFields {
+		name := strings.TrimSpace(field.nameInput.Value())
+		value := field.valueInput.Value()
+		if name == "" && value == "" {
+			continue
+		}
+		credInfo.Fields = append(credInfo.Fields, state.CustomField{
+			Name:  name,
+			Type:  field.fieldType,
+			Value: value,
+		})
+	}
+	return credInfo
+}
+
+// viewportInputs lists every single line input of the form in focus order.
+func (m *Model) viewportInputs() []*textinput.Model {
+	inputs := []*textinput.Model{
+		&m.viewportSourceInput,
+		&m.viewportUsernameInput,
+		&m.viewportPasswordInput,
+		&m.viewportTOTPInput,
+		&m.viewportURLsInput,
+		&m.viewportTagsInput,
+	}
+	for i := range m.viewportFields {
+		inputs = append(inputs,
+			&m.viewportFields[i].nameInput,
+			&m.viewportFields[i].valueInput,
+		)
+	}
+	return inputs
+}
+
+func (m *Model) viewportFocusCount() int {
+	return focusFields + 2*len(m.viewportFields)
+}
+
+func (m *Model) blurViewport() {
+	for _, ti := range m.viewportInputs() {
+		ti.Blur()
+	}
+	m.viewportNotesInput.Blur()
+}
+
+func (m *Model) focusViewport(focus int) tea.Cmd {
+	m.blurViewport()
+	m.viewportNotesInput.SetHeight(viewportNotesHeight)
+	m.viewportFocus = focus
+	switch {
+	case focus == focusNotes:
+		return m.viewportNotesInput.Focus()
+	case focus < focusNotes:
+		ti := m.viewportInputs()[focus]
+		ti.CursorEnd()
+		return ti.Focus()
+	default:
+		ti := m.viewportInputs()[focus-1]
+		ti.CursorEnd()
+		return ti.Focus()
+	}
+}
+
+// focusedField is the index of the custom field being edited, if any.
+func (m *Model) focusedField() (int, bool) {
+	if m.mode != ModeViewport || m.viewportFocus < focusFields {
+		return 0, false
+	}
+	return (m.viewportFocus - focusFields) / 2, true
+}
+
+// journalApplied brings the index, the results and the vault on disk in line
+// with the entries an undo or redo touched.
+func (m *Model) journalApplied(sm *state.Model, entry state.JournalEntry) {
+	for _, id := range entry.IDs() {
+		if credInfo, exists := sm.KeyToCredInfo[id]; exists {
+			fuzzy.UpdateFuzzy(sm, id, credInfo)
+		} else {
+			fuzzy.RemoveFuzzy(sm, id)
+		}
+	}
+	passio.WriteStateCreds(sm)
+	m.populateTopIDs(sm, true)
+	m.populateSuggestions(sm)
+}
+
+type otpTickMsg struct {
+	generation int
+}
+
+// tickOTP keeps the otp countdown moving while a code is on screen.
+func (m *Model) tickOTP() tea.Cmd {
+	if m.viewportTOTPInput.Value() == "" {
+		return nil
+	}
+	if !m.otpTickAt.IsZero() && time.Since(m.otpTickAt) < 2*time.Second {
+		return nil
 	}
+	m.otpTickAt = time.Now()
+	m.otpGeneration++
+	generation := m.otpGeneration
+	return tea.Every(time.Second, func(time.Time) tea.Msg {
+		return otpTickMsg{generation: generation}
+	})
+}
+
+// editingID is the id of the entry in the viewport, which is new when
+// viewportUUID is set and the selected one otherwise.
+func (m *Model) editingID(sm *state.Model) (string, bool) {
+	if m.viewportUUID != "" {
+		return m.viewportUUID, true
+	}
+	_, id, exists := m.getSelectedCredInfo(sm)
+	return id, exists
+}
+
+func (m *Model) closeViewport() {
+	m.setViewportCredInfo(state.CredInfo{}, true)
+	m.viewportUUID = ""
+	m.viewportFocus = focusSource
+	m.mode = ModeNav
+	m.keyMap = navKeyMap
 }
 
 func (m *Model) populateSuggestions(sm *state.Model) {
@@ -37,23 +186,78 @@ func (m *Model) populateSuggestions(sm *state.Model) {
 	m.keyInput.SetSuggestions(suggestions)
 }
 
-func (m *Model) populateTopIDs(sm *state.Model, force bool) {
+// populateTopIDs runs the query again if it changed. One that doesn't parse
+// keeps the previous results and reports why when it is first typed.
+func (m *Model) populateTopIDs(sm *state.Model, force bool) tea.Cmd {
 	query := strings.TrimSpace(m.keyInput.Value())
-	if query != m.lastQuery || len(m.topIDs) == 0 || force {
-		topIDs := fuzzy.QueryTopIDs(sm, query)
-		m.topIDs = topIDs
-		if len(topIDs) == 0 {
-			m.resultPaginator.TotalPages = 1
-		} else {
-			m.resultPaginator.SetTotalPages(len(topIDs))
+	changed := query != m.lastQuery
+	if !changed && !force && (len(m.topIDs) > 0 || m.lastQueryInvalid) {
+		return nil
+	}
+
+	topIDs, err := fuzzy.QueryTopIDs(sm, query, m.byFrecency)
+	if err != nil {
+		topIDs = slices.DeleteFunc(m.topIDs, func(id string) bool {
+			_, exists := sm.KeyToCredInfo[id]
+			return !exists
+		})
+	}
+	m.topIDs = topIDs
+	if len(topIDs) == 0 {
+		m.resultPaginator.TotalPages = 1
+	} else {
+		m.resultPaginator.SetTotalPages(len(topIDs))
+	}
+	m.resultPaginator.Page = 0
+	m.lastQuery = query
+	m.lastQueryInvalid = err != nil
+	m.resultLocOnPage = 0
+
+	if err != nil && changed {
+		return state.NotificationMsg(fmt.Sprintf("Invalid Query: %v", err), state.MessageLevelError)
+	}
+	return nil
+}
+
+// Lock resets the model, wiping every input and result, and remembers the
+// current query, selection and order to restore on the next unlock.
+func (m *Model) Lock(sm *state.Model) {
+	_, id, _ := m.getSelectedCredInfo(sm)
+	query := m.keyInput.Value()
+	byFrecency := m.byFrecency
+
+	*m = Initial()
+	m.byFrecency = byFrecency
+	m.restoring = true
+	m.restoreQuery = query
+	m.restoreID = id
+}
+
+// selectID moves the selection to the entry if it is among the results.
+func (m *Model) selectID(selectID string) {
+	for i, id := range m.topIDs {
+		if id == selectID {
+			m.resultPaginator.Page = i / m.resultPaginator.PerPage
+			m.resultLocOnPage = i % m.resultPaginator.PerPage
+			break
 		}
-		m.resultPaginator.Page = 0
-		m.lastQuery = query
-		m.resultLocOnPage = 0
 	}
 }
 
-func (m *Model) updateSearch(keyMsg tea.KeyMsg, sm *state.Model) {
+// restore brings back the query and selection from before locking, as long
+// as the selected entry is still among the results.
+func (m *Model) restore(sm *state.Model) {
+	m.keyInput.SetValue(m.restoreQuery)
+	m.keyInput.CursorEnd()
+	m.populateTopIDs(sm, true)
+	m.selectID(m.restoreID)
+
+	m.restoring = false
+	m.restoreQuery = ""
+	m.restoreID = ""
+}
+
+func (m *Model) updateSearch(keyMsg tea.KeyMsg, sm *state.Model) tea.Cmd {
 	switch {
 	case key.Matches(keyMsg, searchKeyMap.Confirm):
 		m.keyInput.Blur()
@@ -61,8 +265,9 @@ func (m *Model) updateSearch(keyMsg tea.KeyMsg, sm *state.Model) {
 		m.keyMap = navKeyMap
 		m.helpModel.ShowAll = true
 	}
-	m.populateTopIDs(sm, false)
+	cmd := m.populateTopIDs(sm, false)
 	m.populateSuggestions(sm)
+	return cmd
 }
 
 func (m *Model) updateNav(keyMsg tea.KeyMsg, sm *state.Model) tea.Cmd {
@@ -95,43 +300,180 @@ func (m *Model) updateNav(keyMsg tea.KeyMsg, sm *state.Model) tea.Cmd {
 			m.resultLocOnPage = min(m.resultLocOnPage+1, end-start-1)
 		}
 	case key.Matches(keyMsg, navKeyMap.Copy):
-		if credInfo, _, exists := m.getSelectedCredInfo(sm); exists {
-			clipboard.WriteAll(credInfo.Password)
-			cmds = append(cmds, state.NotificationMsg(
+		if credInfo, id, exists := m.getSelectedCredInfo(sm); exists {
+			cmd, err := sm.CopyToClipboard(credInfo.Password)
+			if err != nil {
+				cmds = append(cmds, state.NotificationMsg(
+					fmt.Sprintf("Could not copy: %v", err),
+					state.MessageLevelError,
+				))
+				break
+			}
+			sm.KeyToCredInfo[id] = credInfo.Used(time.Now())
+			passio.WriteStateUsage(sm)
+			cmds = append(cmds, cmd, state.NotificationMsg(
 				"Password Copied",
 				state.MessageLevelSuccess,
 			))
 		}
+	case key.Matches(keyMsg, navKeyMap.CopyUser):
+		credInfo, id, exists := m.getSelectedCredInfo(sm)
+		if !exists {
+			break
+		}
+		if credInfo.Username == "" {
+			cmds = append(cmds, state.NotificationMsg(
+				"No Username",
+				state.MessageLevelError,
+			))
+			break
+		}
+		cmd, err := sm.CopyToClipboard(credInfo.Username)
+		if err != nil {
+			cmds = append(cmds, state.NotificationMsg(
+				fmt.Sprintf("Could not copy: %v", err),
+				state.MessageLevelError,
+			))
+			break
+		}
+		sm.KeyToCredInfo[id] = credInfo.Used(time.Now())
+		passio.WriteStateUsage(sm)
+		cmds = append(cmds, cmd, state.NotificationMsg(
+			"Username Copied",
+			state.MessageLevelSuccess,
+		))
+	case key.Matches(keyMsg, navKeyMap.CopyOTP):
+		credInfo, id, exists := m.getSelectedCredInfo(sm)
+		if !exists {
+			break
+		}
+		if credInfo.TOTP == "" {
+			cmds = append(cmds, state.NotificationMsg(
+				"No TOTP Secret",
+				state.MessageLevelError,
+			))
+			break
+		}
+		otpKey, err := totp.Parse(credInfo.TOTP)
+		if err != nil {
+			cmds = append(cmds, state.NotificationMsg(
+				fmt.Sprintf("Invalid TOTP: %v", err),
+				state.MessageLevelError,
+			))
+			break
+		}
+		cmd, err := sm.CopyToClipboard(otpKey.Code(time.Now()))
+		if err != nil {
+			cmds = append(cmds, state.NotificationMsg(
+				fmt.Sprintf("Could not copy: %v", err),
+				state.MessageLevelError,
+			))
+			break
+		}
+		sm.KeyToCredInfo[id] = credInfo.Used(time.Now())
+		passio.WriteStateUsage(sm)
+		cmds = append(cmds, cmd, state.NotificationMsg(
+			"Code Copied",
+			state.MessageLevelSuccess,
+		))
+	case key.Matches(keyMsg, navKeyMap.History):
+		credInfo, _, exists := m.getSelectedCredInfo(sm)
+		if !exists {
+			break
+		}
+		if len(credInfo.History) == 0 {
+			cmds = append(cmds, state.NotificationMsg(
+				"No Password History",
+				state.MessageLevelError,
+			))
+			break
+		}
+		m.historyLoc = 0
+		m.mode = ModeHistory
+		m.keyMap = historyKeyMap
 	case key.Matches(keyMsg, navKeyMap.Edit):
 		if _, _, exists := m.getSelectedCredInfo(sm); exists {
-			cmds = append(cmds, m.viewportSourceInput.Focus())
 			m.mode = ModeViewport
 			m.keyMap = viewportKeyMap
+			cmds = append(cmds, m.focusViewport(focusSource))
 		}
 	case key.Matches(keyMsg, navKeyMap.New):
-		cmds = append(cmds, m.viewportSourceInput.Focus())
 		m.mode = ModeViewport
 		m.keyMap = viewportKeyMap
 		m.viewportUUID = uuid.NewString()
-		m.setViewportCredInfo(
-			state.CredInfo{Source: "", Username: "", Password: ""},
-			false,
-		)
+		m.setViewportCredInfo(state.CredInfo{}, false)
+		cmds = append(cmds, m.focusViewport(focusSource))
 	case key.Matches(keyMsg, navKeyMap.Del):
-		if _, id, exists := m.getSelectedCredInfo(sm); exists {
+		if credInfo, id, exists := m.getSelectedCredInfo(sm); exists {
+			before := sm.Snapshot(id)
 			fuzzy.RemoveFuzzy(sm, id)
-			delete(sm.KeyToCredInfo, id)
+			sm.TrashCred(id, time.Now())
+			sm.Record(fmt.Sprintf("Delete %v", credInfo.Source), before)
 			passio.WriteStateCreds(sm)
-			cmds = append(cmds, state.NotificationMsg(
-				"Credentials Deleted",
+			cmds = append(cmds, state.NotificationMsgFor(
+				"Moved to Trash, u to undo",
 				state.MessageLevelSuccess,
+				undoNotificationDuration,
 			))
 			m.populateTopIDs(sm, true)
 			m.populateSuggestions(sm)
 		}
+	case key.Matches(keyMsg, navKeyMap.Undo):
+		entry, undone := sm.Undo()
+		if !undone {
+			cmds = append(cmds, state.NotificationMsg(
+				"Nothing to Undo",
+				state.MessageLevelError,
+			))
+			break
+		}
+		m.journalApplied(sm, entry)
+		cmds = append(cmds, state.NotificationMsg(
+			fmt.Sprintf("Undid %v", entry.Description),
+			state.MessageLevelSuccess,
+		))
+	case key.Matches(keyMsg, navKeyMap.Redo):
+		entry, redone := sm.Redo()
+		if !redone {
+			cmds = append(cmds, state.NotificationMsg(
+				"Nothing to Redo",
+				state.MessageLevelError,
+			))
+			break
+		}
+		m.journalApplied(sm, entry)
+		cmds = append(cmds, state.NotificationMsg(
+			fmt.Sprintf("Redid %v", entry.Description),
+			state.MessageLevelSuccess,
+		))
+	case key.Matches(keyMsg, navKeyMap.Trash):
+		sm.Screen = state.TrashScreen
+		sm.Dirty = true
 	case key.Matches(keyMsg, navKeyMap.ChangeMaster):
 		sm.Screen = state.ChangeMasterScreen
 		sm.Dirty = true
+	case key.Matches(keyMsg, navKeyMap.Backups):
+		sm.Screen = state.BackupScreen
+		sm.Dirty = true
+	case key.Matches(keyMsg, navKeyMap.Import):
+		sm.Screen = state.ImportScreen
+		sm.Dirty = true
+	case key.Matches(keyMsg, navKeyMap.Audit):
+		sm.Screen = state.AuditScreen
+		sm.Dirty = true
+	case key.Matches(keyMsg, navKeyMap.Order):
+		_, id, _ := m.getSelectedCredInfo(sm)
+		m.byFrecency = !m.byFrecency
+		m.populateTopIDs(sm, true)
+		m.selectID(id)
+		order := "Match"
+		if m.byFrecency {
+			order = "Frecency"
+		}
+		cmds = append(cmds, state.NotificationMsg(
+			"Sorted by "+order,
+			state.MessageLevelNotif,
+		))
 	}
 
 	return tea.Batch(cmds...)
@@ -142,65 +484,71 @@ func (m *Model) updateViewport(keyMsg tea.KeyMsg, sm *state.Model) tea.Cmd {
 
 	switch {
 	case key.Matches(keyMsg, viewportKeyMap.Back):
-		m.setViewportCredInfo(
-			state.CredInfo{Source: "", Username: "", Password: ""},
-			true,
-		)
-		m.viewportUUID = ""
-		m.mode = ModeNav
-		m.keyMap = navKeyMap
+		m.closeViewport()
 	case key.Matches(keyMsg, viewportKeyMap.Prev):
-		if m.viewportUsernameInput.Focused() {
-			m.viewportUsernameInput.Blur()
-			cmds = append(cmds, m.viewportSourceInput.Focus())
-		} else if m.viewportPasswordInput.Focused() {
-			m.viewportPasswordInput.Blur()
-			cmds = append(cmds, m.viewportUsernameInput.Focus())
-		} else if m.viewportSourceInput.Focused() {
-			m.viewportSourceInput.Blur()
-			cmds = append(cmds, m.viewportPasswordInput.Focus())
-		}
-		m.viewportSourceInput.CursorEnd()
-		m.viewportUsernameInput.CursorEnd()
-		m.viewportPasswordInput.CursorEnd()
+		count := m.viewportFocusCount()
+		cmds = append(cmds, m.focusViewport((m.viewportFocus+count-1)%count))
 	case key.Matches(keyMsg, viewportKeyMap.Next):
-		if m.viewportUsernameInput.Focused() {
-			m.viewportUsernameInput.Blur()
-			cmds = append(cmds, m.viewportPasswordInput.Focus())
-		} else if m.viewportSourceInput.Focused() {
-			m.viewportSourceInput.Blur()
-			cmds = append(cmds, m.viewportUsernameInput.Focus())
-		} else if m.viewportPasswordInput.Focused() {
-			m.viewportPasswordInput.Blur()
-			cmds = append(cmds, m.viewportSourceInput.Focus())
+		cmds = append(cmds, m.focusViewport((m.viewportFocus+1)%m.viewportFocusCount()))
+	case key.Matches(keyMsg, viewportKeyMap.AddField):
+		m.viewportFields = append(m.viewportFields, newViewportField(state.CustomField{}))
+		cmds = append(cmds, m.focusViewport(m.viewportFocusCount()-2))
+	case key.Matches(keyMsg, viewportKeyMap.RemoveField):
+		if i, ok := m.focusedField(); ok {
+			m.viewportFields = slices.Delete(m.viewportFields, i, i+1)
+			cmds = append(cmds, m.focusViewport(
+				min(focusFields+2*i, m.viewportFocusCount()-1),
+			))
+		}
+	case key.Matches(keyMsg, viewportKeyMap.FieldType):
+		if i, ok := m.focusedField(); ok {
+			field := &m.viewportFields[i]
+			field.setType((field.fieldType + 1) % (state.FieldURL + 1))
+		}
+	case key.Matches(keyMsg, viewportKeyMap.Generate):
+		password, err := passgen.Generate(passgen.DefaultOptions())
+		if err != nil {
+			cmds = append(cmds, state.NotificationMsg(
+				fmt.Sprintf("Could not generate password: %v", err),
+				state.MessageLevelError,
+			))
+			break
 		}
-		m.viewportSourceInput.CursorEnd()
-		m.viewportUsernameInput.CursorEnd()
+		m.viewportPasswordInput.SetValue(password)
 		m.viewportPasswordInput.CursorEnd()
+		cmds = append(cmds, state.NotificationMsg(
+			"Password Generated",
+			state.MessageLevelSuccess,
+		))
 	case key.Matches(keyMsg, viewportKeyMap.Save):
-		id := m.viewportUUID
-		if id == "" {
-			if _, existingId, exists := m.getSelectedCredInfo(sm); exists {
-				id = existingId
-			} else {
-				log.Fatalf("no existing selection when one needed")
+		id, ok := m.editingID(sm)
+		if !ok {
+			log.Fatalf("no existing selection when one needed")
+		}
+		now := time.Now()
+		before := sm.Snapshot(id)
+		credInfo := m.viewportCredInfo()
+		if credInfo.TOTP != "" {
+			if _, err := totp.Parse(credInfo.TOTP); err != nil {
+				cmds = append(cmds, state.NotificationMsg(
+					fmt.Sprintf("Invalid TOTP: %v", err),
+					state.MessageLevelError,
+				))
+				break
 			}
 		}
-		credInfo := state.CredInfo{
-			Source:   m.viewportSourceInput.Value(),
-			Username: m.viewportUsernameInput.Value(),
-			Password: m.viewportPasswordInput.Value(),
+		description := fmt.Sprintf("Add %v", credInfo.Source)
+		if existing, exists := sm.KeyToCredInfo[id]; exists {
+			credInfo = existing.Edited(credInfo, now)
+			description = fmt.Sprintf("Edit %v", credInfo.Source)
+		} else {
+			credInfo = state.NewCredInfo(credInfo, now)
 		}
 		fuzzy.UpdateFuzzy(sm, id, credInfo)
 		sm.KeyToCredInfo[id] = credInfo
+		sm.Record(description, before)
 		passio.WriteStateCreds(sm)
-		m.setViewportCredInfo(
-			state.CredInfo{Source: "", Username: "", Password: ""},
-			true,
-		)
-		m.viewportUUID = ""
-		m.mode = ModeNav
-		m.keyMap = navKeyMap
+		m.closeViewport()
 
 		cmds = append(cmds, state.NotificationMsg(
 			"Credentials Saved",
@@ -214,44 +562,114 @@ func (m *Model) updateViewport(keyMsg tea.KeyMsg, sm *state.Model) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+func (m *Model) updateHistory(keyMsg tea.KeyMsg, sm *state.Model) tea.Cmd {
+	cmds := make([]tea.Cmd, 0)
+
+	credInfo, id, exists := m.getSelectedCredInfo(sm)
+	if !exists || len(credInfo.History) == 0 {
+		m.mode = ModeNav
+		m.keyMap = navKeyMap
+		return nil
+	}
+
+	switch {
+	case key.Matches(keyMsg, historyKeyMap.Back):
+		m.mode = ModeNav
+		m.keyMap = navKeyMap
+	case key.Matches(keyMsg, historyKeyMap.Nav):
+		switch keyMsg.String() {
+		case "up", "k":
+			m.historyLoc = max(m.historyLoc-1, 0)
+		case "down", "j":
+			m.historyLoc = min(m.historyLoc+1, len(credInfo.History)-1)
+		}
+	case key.Matches(keyMsg, historyKeyMap.Copy):
+		cmd, err := sm.CopyToClipboard(credInfo.History[m.historyLoc].Password)
+		if err != nil {
+			cmds = append(cmds, state.NotificationMsg(
+				fmt.Sprintf("Could not copy: %v", err),
+				state.MessageLevelError,
+			))
+			break
+		}
+		cmds = append(cmds, cmd, state.NotificationMsg(
+			"Previous Password Copied",
+			state.MessageLevelSuccess,
+		))
+	case key.Matches(keyMsg, historyKeyMap.Restore):
+		before := sm.Snapshot(id)
+		sm.KeyToCredInfo[id] = credInfo.RestoredPassword(m.historyLoc, time.Now())
+		sm.Record(fmt.Sprintf("Restore Password of %v", credInfo.Source), before)
+		passio.WriteStateCreds(sm)
+		m.mode = ModeNav
+		m.keyMap = navKeyMap
+		cmds = append(cmds, state.NotificationMsg(
+			"Password Restored",
+			state.MessageLevelSuccess,
+		))
+	}
+
+	return tea.Batch(cmds...)
+}
+
 func (m *Model) Update(msg tea.Msg, sm *state.Model) tea.Cmd {
 	cmds := make([]tea.Cmd, 0)
 
-	for _, ti := range []*textinput.Model{
-		&m.keyInput,
-		&m.viewportSourceInput,
-		&m.viewportUsernameInput,
-		&m.viewportPasswordInput,
-	} {
+	for _, ti := range append([]*textinput.Model{&m.keyInput}, m.viewportInputs()...) {
 		tiPointer, cmd := ti.Update(msg)
 		cmds = append(cmds, cmd)
 		*ti = tiPointer
 	}
+	notesInput, cmd := m.viewportNotesInput.Update(msg)
+	cmds = append(cmds, cmd)
+	m.viewportNotesInput = notesInput
 	// manually update the paginator in code later
 
 	switch typedMsg := msg.(type) {
+	case state.CredsReloadedMsg:
+		m.populateTopIDs(sm, true)
+		m.populateSuggestions(sm)
+	case state.SelectEntryMsg:
+		m.keyInput.SetValue("")
+		m.populateTopIDs(sm, true)
+		m.selectID(typedMsg.ID)
+	case otpTickMsg:
+		if typedMsg.generation == m.otpGeneration {
+			m.otpTickAt = time.Time{}
+		}
 	case tea.KeyMsg:
 		switch {
 		case key.Matches(typedMsg, searchKeyMap.Quit):
 			sm.Quitting = true
 			cmds = append(cmds, tea.Quit)
 		case m.mode == ModeSearch:
-			m.updateSearch(typedMsg, sm)
+			cmds = append(cmds, m.updateSearch(typedMsg, sm))
 		case m.mode == ModeNav:
 			cmds = append(cmds, m.updateNav(typedMsg, sm))
 		case m.mode == ModeViewport:
 			cmds = append(cmds, m.updateViewport(typedMsg, sm))
+		case m.mode == ModeHistory:
+			cmds = append(cmds, m.updateHistory(typedMsg, sm))
 		}
 	}
 
 	if sm.Dirty {
-		m.populateTopIDs(sm, false)
+		if m.restoring {
+			m.restore(sm)
+		}
+		cmds = append(cmds, m.populateTopIDs(sm, false))
 		m.populateSuggestions(sm)
 	}
 
 	if credInfo, _, exists := m.getSelectedCredInfo(sm); exists && m.mode != ModeViewport {
 		m.setViewportCredInfo(credInfo, false)
+		// the preview doesn't need room to type into
+		m.viewportNotesInput.SetHeight(min(
+			viewportNotesHeight,
+			m.viewportNotesInput.LineCount(),
+		))
 	}
+	cmds = append(cmds, m.tickOTP())
 
 	return tea.Batch(cmds...)
 }
@@ -2,20 +2,194 @@ package interact
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/strength"
+	"github.com/dismint/dispass/internal/totp"
 	"github.com/dismint/dispass/internal/uconst"
+	"github.com/muesli/reflow/truncate"
 )
 
+func viewTimestamp(label string, t time.Time, zero string) string {
+	value := zero
+	if !t.IsZero() {
+		value = t.Local().Format("2006-01-02 15:04")
+	}
+	return uconst.SymbolStyle.Render(fmt.Sprintf("%-10v", label)) + uconst.TextStyle.Render(value)
+}
+
+// viewBreach warns that the password turned up in a breach check.
+func viewBreach(breach state.Breach) string {
+	seen := fmt.Sprintf("seen %d times", breach.Count)
+	if breach.Count == 1 {
+		seen = "seen once"
+	}
+	return uconst.SymbolStyle.Render(fmt.Sprintf("%-10v", "Breached")) +
+		uconst.MessageLevelErrorStyle.Render(seen)
+}
+
+// strengthBarWidth is how many cells the strength meter has, they fill up
+// at a trillion guesses
+const strengthBarWidth = 10
+
+// viewStrength rates the password being typed, and warns when another entry
+// already has it.
+func (m *Model) viewStrength(sm *state.Model) string {
+	password := m.viewportPasswordInput.Value()
+	result := strength.Check(password, m.viewportSourceInput.Value(), m.viewportUsernameInput.Value())
+	filled := min(max(int(math.Round(math.Log10(result.Guesses)*strengthBarWidth/12)), 1), strengthBarWidth)
+	view := fmt.Sprintf("%v%v%v %v",
+		uconst.SymbolStyle.Render(fmt.Sprintf("%-10v", "Strength")),
+		uconst.StrengthStyles[result.Score].Render(strings.Repeat(string(uconst.PasswordChar), filled)),
+		uconst.HelpSeparatorStyle.Render(strings.Repeat(string(uconst.PasswordChar), strengthBarWidth-filled)),
+		uconst.TextStyle.Render(result.CrackTimeDisplay()),
+	)
+
+	editingID, _ := m.editingID(sm)
+	sources := make([]string, 0)
+	for id, credInfo := range sm.KeyToCredInfo {
+		if id != editingID && credInfo.Password == password {
+			sources = append(sources, credInfo.Source)
+		}
+	}
+	if len(sources) > 0 {
+		slices.Sort(sources)
+		warning := "Also the password of " + sources[0]
+		if len(sources) > 1 {
+			warning += fmt.Sprintf(" and %d more", len(sources)-1)
+		}
+		view += "\n" + uconst.StrengthStyles[0].Render(truncate.StringWithTail(warning, 42, "…"))
+	}
+	return view
+}
+
+// viewOTP shows the current code of a totp secret and how long it lasts.
+func viewOTP(secret string) string {
+	label := uconst.SymbolStyle.Render(fmt.Sprintf("%-10v", "Code"))
+	otpKey, err := totp.Parse(secret)
+	if err != nil {
+		return label + uconst.MessageLevelErrorStyle.Render("invalid secret")
+	}
+	now := time.Now()
+	code := otpKey.Code(now)
+	half := len(code) / 2
+	return fmt.Sprintf("%v%v %v",
+		label,
+		uconst.TextStyle.Render(code[:half]+" "+code[half:]),
+		uconst.SymbolStyle.Render(fmt.Sprintf("%vs", int(otpKey.Remaining(now).Seconds()))),
+	)
+}
+
+// viewHistory lists the previous passwords of the selected entry, masked.
+func (m *Model) viewHistory(credInfo state.CredInfo) string {
+	lines := []string{uconst.TextStyle.Render(
+		fmt.Sprintf("Previous passwords of %v", credInfo.Source),
+	)}
+	for i, previous := range credInfo.History {
+		prefix := " "
+		if i == m.historyLoc {
+			prefix = uconst.SymbolStyle.Render(">")
+		}
+		lines = append(lines, fmt.Sprintf("%v %v  %v",
+			prefix,
+			uconst.TextStyle.Render(previous.Replaced.Local().Format("2006-01-02 15:04")),
+			uconst.SymbolStyle.Render(strings.Repeat(
+				string(uconst.PasswordChar),
+				min(len([]rune(previous.Password)), 20),
+			)),
+		))
+	}
+
+	// keep the selection in view, below the title
+	offset := max(0, m.historyLoc+2-viewportHeight)
+	end := min(len(lines), offset+viewportHeight)
+	if offset > 0 {
+		lines = append(lines[:1], lines[offset+1:end]...)
+	} else {
+		lines = lines[:end]
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (m *Model) viewViewport(sm *state.Model) string {
-	_, _, exists := m.getSelectedCredInfo(sm)
+	credInfo, _, exists := m.getSelectedCredInfo(sm)
+	if exists && m.mode == ModeHistory {
+		return m.viewHistory(credInfo)
+	}
 	if !exists && m.mode != ModeViewport {
 		return "Feeling empty, create new credentials?"
 	}
-	return fmt.Sprintf("%v\n%v\n%v",
-		m.viewportSourceInput.View(),
-		m.viewportUsernameInput.View(),
-		m.viewportPasswordInput.View(),
+	editing := m.mode == ModeViewport
+
+	// outside of editing only the fields that are filled in are previewed
+	lines := make([]string, 0)
+	focusStart, focusEnd := 0, 0
+	addRow := func(view string, focused bool, show bool) {
+		if !editing && !show {
+			return
+		}
+		if editing && focused {
+			focusStart = len(lines)
+		}
+		lines = append(lines, strings.Split(view, "\n")...)
+		if editing && focused {
+			focusEnd = len(lines)
+		}
+	}
+	addRow(m.viewportSourceInput.View(), m.viewportFocus == focusSource, true)
+	addRow(m.viewportUsernameInput.View(), m.viewportFocus == focusUsername, true)
+	passwordRow := m.viewportPasswordInput.View()
+	if editing && m.viewportPasswordInput.Value() != "" {
+		passwordRow += "\n" + m.viewStrength(sm)
+	}
+	addRow(passwordRow, m.viewportFocus == focusPassword, true)
+	if totpValue := m.viewportTOTPInput.Value(); editing {
+		row := m.viewportTOTPInput.View()
+		if strings.TrimSpace(totpValue) != "" {
+			row += "\n" + viewOTP(totpValue)
+		}
+		addRow(row, m.viewportFocus == focusTOTP, true)
+	} else {
+		addRow(viewOTP(totpValue), false, totpValue != "")
+	}
+	addRow(m.viewportURLsInput.View(), m.viewportFocus == focusURLs, m.viewportURLsInput.Value() != "")
+	addRow(m.viewportTagsInput.View(), m.viewportFocus == focusTags, m.viewportTagsInput.Value() != "")
+	addRow(m.viewportNotesInput.View(), m.viewportFocus == focusNotes, m.viewportNotesInput.Value() != "")
+	for i, field := range m.viewportFields {
+		focused := m.viewportFocus == focusFields+2*i || m.viewportFocus == focusFields+2*i+1
+		addRow(field.nameInput.View()+field.valueInput.View(), focused, true)
+	}
+	// a new entry has nothing to show yet
+	if exists && m.viewportUUID == "" {
+		lines = append(lines,
+			viewTimestamp("Created", credInfo.Created, "unknown"),
+			viewTimestamp("Modified", credInfo.Modified, "unknown"),
+			viewTimestamp("Last Used", credInfo.LastUsed, "never"),
+		)
+		if credInfo.Breach.Count > 0 {
+			lines = append(lines, viewBreach(credInfo.Breach))
+		}
+	}
+	// the read only lines at the end come into view along with the last input
+	if editing && m.viewportFocus == m.viewportFocusCount()-1 {
+		focusEnd = len(lines)
+	}
+
+	if len(lines) <= viewportHeight {
+		return strings.Join(lines, "\n")
+	}
+	offset := min(max(0, focusEnd-viewportHeight), focusStart)
+	end := min(len(lines), offset+viewportHeight)
+	return fmt.Sprintf("%v\n%v",
+		strings.Join(lines[offset:end], "\n"),
+		uconst.TextStyle.Render(fmt.Sprintf("%v %d above  %v %d below",
+			uconst.SymbolStyle.Render("↑"), offset,
+			uconst.SymbolStyle.Render("↓"), len(lines)-end,
+		)),
 	)
 }
 
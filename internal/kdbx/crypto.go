@@ -0,0 +1,368 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	argon2d "github.com/tobischo/argon2"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/twofish"
+)
+
+type KDF int
+
+const (
+	KDFArgon2id KDF = iota
+	KDFArgon2d
+	KDFAES
+)
+
+func (k KDF) String() string {
+	switch k {
+	case KDFArgon2id:
+		return "argon2id"
+	case KDFArgon2d:
+		return "argon2d"
+	case KDFAES:
+		return "aes"
+	}
+	return fmt.Sprintf("kdf(%d)", int(k))
+}
+
+type Cipher int
+
+const (
+	CipherAES256 Cipher = iota
+	CipherChaCha20
+	// twofish is only read, keepassxc no longer offers it for new databases
+	CipherTwofish
+)
+
+func (c Cipher) String() string {
+	switch c {
+	case CipherAES256:
+		return "aes"
+	case CipherChaCha20:
+		return "chacha20"
+	case CipherTwofish:
+		return "twofish"
+	}
+	return fmt.Sprintf("cipher(%d)", int(c))
+}
+
+func cipherFromUUID(id []byte) (Cipher, error) {
+	switch {
+	case bytes.Equal(id, uuidAES256):
+		return CipherAES256, nil
+	case bytes.Equal(id, uuidChaCha20):
+		return CipherChaCha20, nil
+	case bytes.Equal(id, uuidTwofish):
+		return CipherTwofish, nil
+	}
+	return 0, fmt.Errorf("unknown cipher %x", id)
+}
+
+func (c Cipher) uuid() []byte {
+	switch c {
+	case CipherChaCha20:
+		return uuidChaCha20
+	case CipherTwofish:
+		return uuidTwofish
+	}
+	return uuidAES256
+}
+
+func (c Cipher) ivLen() int {
+	if c == CipherChaCha20 {
+		return chacha20.NonceSize
+	}
+	return aes.BlockSize
+}
+
+// limits on what a database may ask of us, generous next to what keepassxc
+// lets you pick so only corrupt or hostile files hit them
+const (
+	maxArgon2Memory = 4 << 30
+	maxArgon2Time   = 1 << 16
+	maxAESRounds    = 1 << 32
+)
+
+type kdfParams struct {
+	kdf         KDF
+	salt        []byte
+	rounds      uint64 // aes rounds or argon2 iterations
+	memory      uint64 // bytes
+	parallelism uint32
+}
+
+func kdfFromDictionary(dict variantDictionary) (kdfParams, error) {
+	id, err := dict.bytes("$UUID")
+	if err != nil {
+		return kdfParams{}, err
+	}
+
+	var p kdfParams
+	switch {
+	case bytes.Equal(id, uuidAESKDF3), bytes.Equal(id, uuidAESKDF4):
+		p.kdf = KDFAES
+		if p.salt, err = dict.bytes("S"); err != nil {
+			return kdfParams{}, err
+		}
+		if p.rounds, err = dict.uint("R"); err != nil {
+			return kdfParams{}, err
+		}
+		if len(p.salt) != 32 || p.rounds > maxAESRounds {
+			return kdfParams{}, fmt.Errorf("invalid aes-kdf parameters")
+		}
+		return p, nil
+	case bytes.Equal(id, uuidArgon2d):
+		p.kdf = KDFArgon2d
+	case bytes.Equal(id, uuidArgon2id):
+		p.kdf = KDFArgon2id
+	default:
+		return kdfParams{}, fmt.Errorf("unknown kdf %x", id)
+	}
+
+	if p.salt, err = dict.bytes("S"); err != nil {
+		return kdfParams{}, err
+	}
+	if p.rounds, err = dict.uint("I"); err != nil {
+		return kdfParams{}, err
+	}
+	if p.memory, err = dict.uint("M"); err != nil {
+		return kdfParams{}, err
+	}
+	parallelism, err := dict.uint("P")
+	if err != nil {
+		return kdfParams{}, err
+	}
+	p.parallelism = uint32(parallelism)
+	version, err := dict.uint("V")
+	if err != nil {
+		return kdfParams{}, err
+	}
+	if version != argon2.Version {
+		return kdfParams{}, fmt.Errorf("argon2 version %#x is not supported", version)
+	}
+	for _, name := range []string{"K", "A"} {
+		if item, ok := dict.get(name); ok && len(item.value) > 0 {
+			return kdfParams{}, fmt.Errorf("argon2 parameter %v is not supported", name)
+		}
+	}
+	if p.rounds == 0 || p.rounds > maxArgon2Time || p.memory < 8<<10 || p.memory > maxArgon2Memory ||
+		p.parallelism == 0 || p.parallelism > 255 {
+		return kdfParams{}, fmt.Errorf("invalid %v parameters", p.kdf)
+	}
+	return p, nil
+}
+
+func (p kdfParams) dictionary() variantDictionary {
+	if p.kdf == KDFAES {
+		return variantDictionary{
+			bytesItem("$UUID", uuidAESKDF4),
+			uint64Item("R", p.rounds),
+			bytesItem("S", p.salt),
+		}
+	}
+	id := uuidArgon2id
+	if p.kdf == KDFArgon2d {
+		id = uuidArgon2d
+	}
+	return variantDictionary{
+		bytesItem("$UUID", id),
+		uint64Item("I", p.rounds),
+		uint64Item("M", p.memory),
+		uint32Item("P", p.parallelism),
+		bytesItem("S", p.salt),
+		uint32Item("V", argon2.Version),
+	}
+}
+
+// transform derives the key the master and hmac keys are built from.
+func (p kdfParams) transform(composite []byte) ([]byte, error) {
+	switch p.kdf {
+	case KDFArgon2d:
+		return argon2d.DKey(composite, p.salt, uint32(p.rounds), uint32(p.memory/1024), uint8(p.parallelism), 32), nil
+	case KDFArgon2id:
+		return argon2.IDKey(composite, p.salt, uint32(p.rounds), uint32(p.memory/1024), uint8(p.parallelism), 32), nil
+	}
+
+	block, err := aes.NewCipher(p.salt)
+	if err != nil {
+		return nil, err
+	}
+	key := append([]byte{}, composite...)
+	for range p.rounds {
+		block.Encrypt(key[:16], key[:16])
+		block.Encrypt(key[16:], key[16:])
+	}
+	hash := sha256.Sum256(key)
+	return hash[:], nil
+}
+
+// compositeKey is what a password only database is keyed with.
+func compositeKey(password string) []byte {
+	inner := sha256.Sum256([]byte(password))
+	outer := sha256.Sum256(inner[:])
+	return outer[:]
+}
+
+type keys struct {
+	cipher []byte
+	hmac   []byte
+}
+
+func deriveKeys(h header, password string) (keys, error) {
+	transformed, err := h.kdf.transform(compositeKey(password))
+	if err != nil {
+		return keys{}, err
+	}
+	cipherKey := sha256.New()
+	cipherKey.Write(h.masterSeed)
+	cipherKey.Write(transformed)
+	hmacKey := sha512.New()
+	hmacKey.Write(h.masterSeed)
+	hmacKey.Write(transformed)
+	hmacKey.Write([]byte{1})
+	return keys{cipher: cipherKey.Sum(nil), hmac: hmacKey.Sum(nil)}, nil
+}
+
+// blockHMAC authenticates one payload block, the header uses index max uint64.
+func (k keys) blockHMAC(index uint64, data []byte) []byte {
+	key := sha512.New()
+	binary.Write(key, binary.LittleEndian, index)
+	key.Write(k.hmac)
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	binary.Write(mac, binary.LittleEndian, index)
+	binary.Write(mac, binary.LittleEndian, uint32(len(data)))
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (k keys) headerHMAC(raw []byte) []byte {
+	key := sha512.New()
+	binary.Write(key, binary.LittleEndian, ^uint64(0))
+	key.Write(k.hmac)
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	mac.Write(raw)
+	return mac.Sum(nil)
+}
+
+const blockSize = 1 << 20
+
+// readBlocks checks and joins the hmac blocks of the payload. Block lengths
+// are checked against what is left before anything is allocated for them.
+func readBlocks(r *bytes.Reader, k keys) ([]byte, error) {
+	var payload bytes.Buffer
+	for index := uint64(0); ; index++ {
+		mac := make([]byte, sha256.Size)
+		var length uint32
+		if _, err := io.ReadFull(r, mac); err != nil {
+			return nil, ErrCorrupt
+		}
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, ErrCorrupt
+		}
+		if int64(length) > int64(r.Len()) {
+			return nil, ErrCorrupt
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, ErrCorrupt
+		}
+		if !hmac.Equal(mac, k.blockHMAC(index, data)) {
+			return nil, ErrCorrupt
+		}
+		if length == 0 {
+			return payload.Bytes(), nil
+		}
+		payload.Write(data)
+	}
+}
+
+func writeBlocks(w *bytes.Buffer, k keys, payload []byte) {
+	for index := uint64(0); ; index++ {
+		data := payload[:min(blockSize, len(payload))]
+		payload = payload[len(data):]
+		w.Write(k.blockHMAC(index, data))
+		binary.Write(w, binary.LittleEndian, uint32(len(data)))
+		w.Write(data)
+		if len(data) == 0 {
+			return
+		}
+	}
+}
+
+func decrypt(c Cipher, key, iv, data []byte) ([]byte, error) {
+	if c == CipherChaCha20 {
+		stream, err := chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			return nil, err
+		}
+		plain := make([]byte, len(data))
+		stream.XORKeyStream(plain, data)
+		return plain, nil
+	}
+
+	block, err := blockCipher(c, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, ErrCorrupt
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > block.BlockSize() {
+		return nil, ErrCorrupt
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+func encrypt(c Cipher, key, iv, data []byte) ([]byte, error) {
+	if c == CipherChaCha20 {
+		stream, err := chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			return nil, err
+		}
+		sealed := make([]byte, len(data))
+		stream.XORKeyStream(sealed, data)
+		return sealed, nil
+	}
+
+	block, err := blockCipher(c, key)
+	if err != nil {
+		return nil, err
+	}
+	padding := block.BlockSize() - len(data)%block.BlockSize()
+	data = append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	sealed := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(sealed, data)
+	return sealed, nil
+}
+
+func blockCipher(c Cipher, key []byte) (cipher.Block, error) {
+	if c == CipherTwofish {
+		return twofish.NewCipher(key)
+	}
+	return aes.NewCipher(key)
+}
+
+// inner random stream ids, only chacha20 is used by kdbx 4
+const streamChaCha20 = 3
+
+// innerStream hides protected values within the xml, every value is xored
+// with the next bytes of a single stream in document order.
+func innerStream(key []byte) (*chacha20.Cipher, error) {
+	hash := sha512.Sum512(key)
+	return chacha20.NewUnauthenticatedCipher(hash[:32], hash[32:32+chacha20.NonceSize])
+}
@@ -0,0 +1,299 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// outer header layout, all integers little endian:
+//
+//	signature  uint32 0x9AA2D903, uint32 0xB54BFB67
+//	version    uint16 minor, uint16 major
+//	fields     uint8 id, uint32 length, data, until the end field
+//	hashes     sha256 of the header, hmac-sha256 of the header
+//	payload    hmac checked blocks of the encrypted, compressed inner data
+
+const (
+	signature1 = 0x9AA2D903
+	signature2 = 0xB54BFB67
+
+	majorVersion = 4
+	minorVersion = 1
+)
+
+// outer header field ids
+const (
+	fieldEnd              = 0
+	fieldCipherID         = 2
+	fieldCompressionFlags = 3
+	fieldMasterSeed       = 4
+	fieldEncryptionIV     = 7
+	fieldKDFParameters    = 11
+)
+
+// inner header field ids
+const (
+	innerFieldEnd       = 0
+	innerFieldStreamID  = 1
+	innerFieldStreamKey = 2
+	innerFieldBinary    = 3
+)
+
+const compressionGzip = 1
+
+var (
+	uuidAES256   = []byte{0x31, 0xC1, 0xF2, 0xE6, 0xBF, 0x71, 0x43, 0x50, 0xBE, 0x58, 0x05, 0x21, 0x6A, 0xFC, 0x5A, 0xFF}
+	uuidChaCha20 = []byte{0xD6, 0x03, 0x8A, 0x2B, 0x8B, 0x6F, 0x4C, 0xB5, 0xA5, 0x24, 0x33, 0x9A, 0x31, 0xDB, 0xB5, 0x9A}
+	uuidTwofish  = []byte{0xAD, 0x68, 0xF2, 0x9F, 0x57, 0x6F, 0x4B, 0xB9, 0xA3, 0x6A, 0xD4, 0x7A, 0xF9, 0x65, 0x34, 0x6C}
+
+	uuidArgon2d  = []byte{0xEF, 0x63, 0x6D, 0xDF, 0x8C, 0x29, 0x44, 0x4B, 0x91, 0xF7, 0xA9, 0xA4, 0x03, 0xE3, 0x0A, 0x0C}
+	uuidArgon2id = []byte{0x9E, 0x29, 0x8B, 0x19, 0x56, 0xDB, 0x47, 0x73, 0xB2, 0x3D, 0xFC, 0x3E, 0xC6, 0xF0, 0xA1, 0xE6}
+	// keepass itself writes the kdbx 3 id for aes-kdf, keepassxc its own
+	uuidAESKDF3 = []byte{0xC9, 0xD9, 0xF3, 0x9A, 0x62, 0x8A, 0x44, 0x60, 0xBF, 0x74, 0x0D, 0x08, 0xC1, 0x8A, 0x4F, 0xEA}
+	uuidAESKDF4 = []byte{0x7C, 0x02, 0xBB, 0x82, 0x79, 0xA7, 0x4A, 0xC0, 0x92, 0x7D, 0x11, 0x4A, 0x00, 0x64, 0x82, 0x38}
+)
+
+type header struct {
+	cipher     Cipher
+	compressed bool
+	masterSeed []byte
+	iv         []byte
+	kdf        kdfParams
+}
+
+// readHeader parses the outer header, returning it along with its raw bytes
+// for the hashes that follow.
+func readHeader(r *bytes.Reader) (header, []byte, error) {
+	start := r.Size() - int64(r.Len())
+
+	var fixed struct {
+		Signature1 uint32
+		Signature2 uint32
+		Minor      uint16
+		Major      uint16
+	}
+	if err := binary.Read(r, binary.LittleEndian, &fixed); err != nil {
+		return header{}, nil, ErrNotKDBX
+	}
+	if fixed.Signature1 != signature1 || fixed.Signature2 != signature2 {
+		return header{}, nil, ErrNotKDBX
+	}
+	if fixed.Major != majorVersion {
+		return header{}, nil, fmt.Errorf("kdbx %d.%d is not supported, save the database as kdbx 4", fixed.Major, fixed.Minor)
+	}
+
+	var h header
+	var seenCipher, seenKDF bool
+	for {
+		id, data, err := readField(r)
+		if err != nil {
+			return header{}, nil, err
+		}
+		if id == fieldEnd {
+			break
+		}
+		switch id {
+		case fieldCipherID:
+			if h.cipher, err = cipherFromUUID(data); err != nil {
+				return header{}, nil, err
+			}
+			seenCipher = true
+		case fieldCompressionFlags:
+			if len(data) != 4 {
+				return header{}, nil, ErrCorrupt
+			}
+			h.compressed = binary.LittleEndian.Uint32(data) == compressionGzip
+		case fieldMasterSeed:
+			h.masterSeed = data
+		case fieldEncryptionIV:
+			h.iv = data
+		case fieldKDFParameters:
+			dict, err := readVariantDictionary(data)
+			if err != nil {
+				return header{}, nil, err
+			}
+			if h.kdf, err = kdfFromDictionary(dict); err != nil {
+				return header{}, nil, err
+			}
+			seenKDF = true
+		}
+	}
+	if !seenCipher || !seenKDF || len(h.masterSeed) != 32 || len(h.iv) != h.cipher.ivLen() {
+		return header{}, nil, ErrCorrupt
+	}
+
+	end := r.Size() - int64(r.Len())
+	raw := make([]byte, end-start)
+	if _, err := r.ReadAt(raw, start); err != nil {
+		return header{}, nil, ErrCorrupt
+	}
+	return h, raw, nil
+}
+
+func readField(r io.Reader) (byte, []byte, error) {
+	var id [1]byte
+	var length uint32
+	if _, err := io.ReadFull(r, id[:]); err != nil {
+		return 0, nil, ErrCorrupt
+	}
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return 0, nil, ErrCorrupt
+	}
+	if length > 64<<20 {
+		return 0, nil, ErrCorrupt
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, ErrCorrupt
+	}
+	return id[0], data, nil
+}
+
+func writeField(w *bytes.Buffer, id byte, data []byte) {
+	w.WriteByte(id)
+	binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+}
+
+func (h header) marshal() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{signature1, signature2})
+	binary.Write(&buf, binary.LittleEndian, []uint16{minorVersion, majorVersion})
+
+	compression := make([]byte, 4)
+	if h.compressed {
+		binary.LittleEndian.PutUint32(compression, compressionGzip)
+	}
+	writeField(&buf, fieldCipherID, h.cipher.uuid())
+	writeField(&buf, fieldCompressionFlags, compression)
+	writeField(&buf, fieldMasterSeed, h.masterSeed)
+	writeField(&buf, fieldEncryptionIV, h.iv)
+	writeField(&buf, fieldKDFParameters, h.kdf.dictionary().marshal())
+	writeField(&buf, fieldEnd, []byte("\r\n\r\n"))
+	return buf.Bytes()
+}
+
+// variant dictionary value types
+const (
+	variantUInt32 = 0x04
+	variantUInt64 = 0x05
+	variantBool   = 0x08
+	variantInt32  = 0x0C
+	variantInt64  = 0x0D
+	variantString = 0x18
+	variantBytes  = 0x42
+)
+
+const variantVersion = 0x0100
+
+type variantItem struct {
+	kind  byte
+	name  string
+	value []byte
+}
+
+// variantDictionary is the typed key value format of the kdf parameters.
+type variantDictionary []variantItem
+
+func readVariantDictionary(data []byte) (variantDictionary, error) {
+	r := bytes.NewReader(data)
+	var version uint16
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, ErrCorrupt
+	}
+	if version>>8 > variantVersion>>8 {
+		return nil, fmt.Errorf("unsupported kdf parameters version %#x", version)
+	}
+
+	var dict variantDictionary
+	for {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrCorrupt
+		}
+		if kind == 0 {
+			return dict, nil
+		}
+		name, err := readSized(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readSized(r)
+		if err != nil {
+			return nil, err
+		}
+		dict = append(dict, variantItem{kind: kind, name: string(name), value: value})
+	}
+}
+
+func readSized(r *bytes.Reader) ([]byte, error) {
+	var length int32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil || length < 0 || int(length) > r.Len() {
+		return nil, ErrCorrupt
+	}
+	data := make([]byte, length)
+	r.Read(data)
+	return data, nil
+}
+
+func (d variantDictionary) marshal() []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint16(variantVersion))
+	for _, item := range d {
+		buf.WriteByte(item.kind)
+		binary.Write(&buf, binary.LittleEndian, int32(len(item.name)))
+		buf.WriteString(item.name)
+		binary.Write(&buf, binary.LittleEndian, int32(len(item.value)))
+		buf.Write(item.value)
+	}
+	buf.WriteByte(0)
+	return buf.Bytes()
+}
+
+func (d variantDictionary) get(name string) (variantItem, bool) {
+	for _, item := range d {
+		if item.name == name {
+			return item, true
+		}
+	}
+	return variantItem{}, false
+}
+
+func (d variantDictionary) uint(name string) (uint64, error) {
+	item, ok := d.get(name)
+	if !ok {
+		return 0, fmt.Errorf("kdf parameter %v is missing", name)
+	}
+	switch {
+	case item.kind == variantUInt32 && len(item.value) == 4:
+		return uint64(binary.LittleEndian.Uint32(item.value)), nil
+	case item.kind == variantUInt64 && len(item.value) == 8:
+		return binary.LittleEndian.Uint64(item.value), nil
+	}
+	return 0, ErrCorrupt
+}
+
+func (d variantDictionary) bytes(name string) ([]byte, error) {
+	item, ok := d.get(name)
+	if !ok {
+		return nil, fmt.Errorf("kdf parameter %v is missing", name)
+	}
+	if item.kind != variantBytes {
+		return nil, ErrCorrupt
+	}
+	return item.value, nil
+}
+
+func uint32Item(name string, value uint32) variantItem {
+	return variantItem{kind: variantUInt32, name: name, value: binary.LittleEndian.AppendUint32(nil, value)}
+}
+
+func uint64Item(name string, value uint64) variantItem {
+	return variantItem{kind: variantUInt64, name: name, value: binary.LittleEndian.AppendUint64(nil, value)}
+}
+
+func bytesItem(name string, value []byte) variantItem {
+	return variantItem{kind: variantBytes, name: name, value: value}
+}
@@ -0,0 +1,410 @@
+// Package kdbx reads and writes KeePass KDBX 4 databases protected by a
+// password, with argon2 or aes-kdf key derivation and an aes or chacha20
+// payload.
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotKDBX           = errors.New("not a keepass database")
+	ErrCorrupt           = errors.New("keepass database is corrupt or truncated")
+	ErrIncorrectPassword = errors.New("incorrect keepass password")
+)
+
+type UUID [16]byte
+
+func NewUUID() UUID {
+	var id UUID
+	rand.Read(id[:])
+	return id
+}
+
+func (id UUID) String() string {
+	return base64.StdEncoding.EncodeToString(id[:])
+}
+
+func parseUUID(s string) UUID {
+	var id UUID
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err == nil && len(raw) == len(id) {
+		copy(id[:], raw)
+	}
+	return id
+}
+
+type Database struct {
+	Name string
+	Root Group
+	// RecycleBin is the group deleted entries are moved to, zero if none
+	RecycleBin UUID
+}
+
+type Group struct {
+	UUID    UUID
+	Name    string
+	Entries []Entry
+	Groups  []Group
+}
+
+type Entry struct {
+	UUID    UUID
+	Tags    []string
+	Times   Times
+	Strings []String
+	// Attachments are the names of attached files, their content is not kept
+	Attachments []string
+	// History holds previous versions of the entry, oldest first
+	History []Entry
+}
+
+type String struct {
+	Key       string
+	Value     string
+	Protected bool
+}
+
+type Times struct {
+	Created  time.Time
+	Modified time.Time
+	Accessed time.Time
+	Expires  bool
+	Expiry   time.Time
+}
+
+// standard string keys, everything else is a custom string
+const (
+	KeyTitle    = "Title"
+	KeyUserName = "UserName"
+	KeyPassword = "Password"
+	KeyURL      = "URL"
+	KeyNotes    = "Notes"
+)
+
+// Get returns the value of a string of the entry.
+func (e Entry) Get(key string) string {
+	for _, s := range e.Strings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+type Options struct {
+	KDF    KDF
+	Cipher Cipher
+	// Iterations is the argon2 time cost or the number of aes-kdf rounds
+	Iterations uint64
+	// Memory and Parallelism only apply to argon2, memory is in bytes
+	Memory      uint64
+	Parallelism uint32
+}
+
+const DefaultAESRounds = 2_000_000
+
+// DefaultOptions matches what keepassxc picks for new databases.
+func DefaultOptions() Options {
+	return Options{
+		KDF:         KDFArgon2id,
+		Cipher:      CipherAES256,
+		Iterations:  10,
+		Memory:      64 << 20,
+		Parallelism: 2,
+	}
+}
+
+// Read decrypts and parses a database.
+func Read(data []byte, password string) (*Database, error) {
+	r := bytes.NewReader(data)
+	h, raw, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]byte, 2*sha256.Size)
+	if _, err := io.ReadFull(r, hashes); err != nil {
+		return nil, ErrCorrupt
+	}
+	if sum := sha256.Sum256(raw); !bytes.Equal(sum[:], hashes[:sha256.Size]) {
+		return nil, ErrCorrupt
+	}
+	k, err := deriveKeys(h, password)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(k.headerHMAC(raw), hashes[sha256.Size:]) {
+		return nil, ErrIncorrectPassword
+	}
+
+	sealed, err := readBlocks(r, k)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := decrypt(h.cipher, k.cipher, h.iv, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if h.compressed {
+		gz, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, ErrCorrupt
+		}
+		if payload, err = io.ReadAll(gz); err != nil {
+			return nil, ErrCorrupt
+		}
+	}
+
+	inner := bytes.NewReader(payload)
+	var streamID uint32
+	var streamKey []byte
+	for {
+		id, data, err := readField(inner)
+		if err != nil {
+			return nil, err
+		}
+		if id == innerFieldEnd {
+			break
+		}
+		switch id {
+		case innerFieldStreamID:
+			if len(data) != 4 {
+				return nil, ErrCorrupt
+			}
+			streamID = binary.LittleEndian.Uint32(data)
+		case innerFieldStreamKey:
+			streamKey = data
+		case innerFieldBinary:
+			// attachments are not imported
+		}
+	}
+	if streamID != streamChaCha20 {
+		return nil, fmt.Errorf("inner stream %d is not supported", streamID)
+	}
+	stream, err := innerStream(streamKey)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := io.ReadAll(inner)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	if document, err = crypt(document, stream, false); err != nil {
+		return nil, fmt.Errorf("failed to read keepass xml: %w", err)
+	}
+	var file xmlFile
+	if err := xml.Unmarshal(document, &file); err != nil {
+		return nil, fmt.Errorf("failed to read keepass xml: %w", err)
+	}
+
+	db := &Database{
+		Name: file.Meta.DatabaseName,
+		Root: groupFromXML(file.Root.Group),
+	}
+	if file.Meta.RecycleBinEnabled {
+		db.RecycleBin = parseUUID(file.Meta.RecycleBinUUID)
+	}
+	return db, nil
+}
+
+// Write encrypts the database to w.
+func Write(w io.Writer, db *Database, password string, opts Options) error {
+	h := header{
+		cipher:     opts.Cipher,
+		compressed: true,
+		masterSeed: random(32),
+		iv:         random(opts.Cipher.ivLen()),
+		kdf: kdfParams{
+			kdf:         opts.KDF,
+			salt:        random(32),
+			rounds:      opts.Iterations,
+			memory:      opts.Memory,
+			parallelism: opts.Parallelism,
+		},
+	}
+	raw := h.marshal()
+	k, err := deriveKeys(h, password)
+	if err != nil {
+		return err
+	}
+
+	streamKey := random(64)
+	stream, err := innerStream(streamKey)
+	if err != nil {
+		return err
+	}
+	file := xmlFile{
+		Meta: xmlMeta{
+			Generator:        "dispass",
+			DatabaseName:     db.Name,
+			MemoryProtection: xmlMemoryProtection{ProtectPassword: true},
+		},
+		Root: xmlRoot{Group: groupToXML(db.Root)},
+	}
+	document, err := xml.MarshalIndent(file, "", "\t")
+	if err != nil {
+		return err
+	}
+	if document, err = crypt(append([]byte(xml.Header), document...), stream, true); err != nil {
+		return err
+	}
+
+	var payload bytes.Buffer
+	var innerHeader bytes.Buffer
+	writeField(&innerHeader, innerFieldStreamID, binary.LittleEndian.AppendUint32(nil, streamChaCha20))
+	writeField(&innerHeader, innerFieldStreamKey, streamKey)
+	writeField(&innerHeader, innerFieldEnd, nil)
+	gz := gzip.NewWriter(&payload)
+	gz.Write(innerHeader.Bytes())
+	gz.Write(document)
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	sealed, err := encrypt(h.cipher, k.cipher, h.iv, payload.Bytes())
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	out.Write(raw)
+	sum := sha256.Sum256(raw)
+	out.Write(sum[:])
+	out.Write(k.headerHMAC(raw))
+	writeBlocks(&out, k, sealed)
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+func random(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
+
+func groupFromXML(g xmlGroup) Group {
+	group := Group{UUID: parseUUID(g.UUID), Name: g.Name}
+	for _, e := range g.Entries {
+		group.Entries = append(group.Entries, entryFromXML(e))
+	}
+	for _, child := range g.Groups {
+		group.Groups = append(group.Groups, groupFromXML(child))
+	}
+	return group
+}
+
+func entryFromXML(e xmlEntry) Entry {
+	entry := Entry{
+		UUID: parseUUID(e.UUID),
+		Tags: splitTags(e.Tags),
+		Times: Times{
+			Created:  time.Time(e.Times.CreationTime),
+			Modified: time.Time(e.Times.LastModificationTime),
+			Accessed: time.Time(e.Times.LastAccessTime),
+			Expires:  bool(e.Times.Expires),
+			Expiry:   time.Time(e.Times.ExpiryTime),
+		},
+	}
+	for _, s := range e.Strings {
+		entry.Strings = append(entry.Strings, String{
+			Key:       s.Key,
+			Value:     s.Value.Content,
+			Protected: bool(s.Value.Protected),
+		})
+	}
+	for _, binary := range e.Binaries {
+		entry.Attachments = append(entry.Attachments, binary.Key)
+	}
+	if e.History != nil {
+		for _, previous := range e.History.Entries {
+			entry.History = append(entry.History, entryFromXML(previous))
+		}
+	}
+	return entry
+}
+
+// splitTags reads tags separated by semicolons, or commas in older files.
+func splitTags(s string) []string {
+	tags := make([]string, 0)
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func groupToXML(g Group) xmlGroup {
+	now := xmlTime(time.Now())
+	group := xmlGroup{
+		UUID:       uuidOrNew(g.UUID).String(),
+		Name:       g.Name,
+		Times:      xmlTimes{CreationTime: now, LastModificationTime: now, LastAccessTime: now, LocationChanged: now},
+		IsExpanded: true,
+	}
+	for _, e := range g.Entries {
+		group.Entries = append(group.Entries, entryToXML(e))
+	}
+	for _, child := range g.Groups {
+		group.Groups = append(group.Groups, groupToXML(child))
+	}
+	return group
+}
+
+func entryToXML(e Entry) xmlEntry {
+	now := time.Now()
+	entry := xmlEntry{
+		UUID: uuidOrNew(e.UUID).String(),
+		Tags: strings.Join(e.Tags, ";"),
+		Times: xmlTimes{
+			CreationTime:         xmlTime(timeOr(e.Times.Created, now)),
+			LastModificationTime: xmlTime(timeOr(e.Times.Modified, now)),
+			LastAccessTime:       xmlTime(timeOr(e.Times.Accessed, now)),
+			ExpiryTime:           xmlTime(timeOr(e.Times.Expiry, now)),
+			Expires:              xmlBool(e.Times.Expires),
+			LocationChanged:      xmlTime(now),
+		},
+	}
+	for _, s := range e.Strings {
+		entry.Strings = append(entry.Strings, xmlString{
+			Key:   s.Key,
+			Value: xmlValue{Content: s.Value, Protected: xmlBool(s.Protected)},
+		})
+	}
+	if len(e.History) > 0 {
+		entry.History = &xmlHistory{}
+		for _, previous := range e.History {
+			// versions of an entry share its uuid
+			previous.UUID = parseUUID(entry.UUID)
+			entry.History.Entries = append(entry.History.Entries, entryToXML(previous))
+		}
+	}
+	return entry
+}
+
+func uuidOrNew(id UUID) UUID {
+	if id == (UUID{}) {
+		return NewUUID()
+	}
+	return id
+}
+
+func timeOr(t, fallback time.Time) time.Time {
+	if t.IsZero() {
+		return fallback
+	}
+	return t
+}
@@ -0,0 +1,189 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// the keepass-*.kdbx fixtures were saved by KeePass, see testdata/README.md
+const fixturePassword = "abcdefg12345678"
+
+func readFixture(t *testing.T, name, password string) *Database {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := Read(data, password)
+	if err != nil {
+		t.Fatalf("read %v: %v", name, err)
+	}
+	return db
+}
+
+func TestReadKeePassFixtures(t *testing.T) {
+	for _, name := range []string{
+		"keepass-argon2d-aes.kdbx",
+		"keepass-argon2d-chacha20.kdbx",
+		"keepass-uncompressed.kdbx",
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := readFixture(t, name, fixturePassword)
+			if db.Root.Name != "example" || len(db.Root.Groups) < 2 {
+				t.Fatalf("unexpected root group %q with %d groups", db.Root.Name, len(db.Root.Groups))
+			}
+			if db.RecycleBin == (UUID{}) {
+				t.Errorf("recycle bin not read")
+			}
+
+			general, windows := db.Root.Groups[0], db.Root.Groups[1]
+			if general.Name != "General" || windows.Name != "Windows" {
+				t.Fatalf("unexpected groups %q and %q", general.Name, windows.Name)
+			}
+			sample := general.Entries[0]
+			for key, want := range map[string]string{
+				KeyTitle:    "Sample Entry",
+				KeyUserName: "User Name",
+				KeyPassword: "Password",
+				KeyURL:      "http://keepass.info/",
+				KeyNotes:    "Notes",
+			} {
+				if got := sample.Get(key); got != want {
+					t.Errorf("%v: got %q, want %q", key, got, want)
+				}
+			}
+			if want := time.Date(2015, 6, 19, 15, 38, 42, 0, time.UTC); !sample.Times.Modified.Equal(want) {
+				t.Errorf("modified: got %v, want %v", sample.Times.Modified, want)
+			}
+
+			// a protected custom string after the protected password checks
+			// the inner stream is consumed in document order
+			copied := windows.Entries[1]
+			if got := copied.Get("test"); got != "prova" {
+				t.Errorf("custom string: got %q, want %q", got, "prova")
+			}
+			if len(copied.History) != 1 || len(copied.Attachments) != 1 || copied.Attachments[0] != "example.txt" {
+				t.Errorf("got %d history entries and attachments %v", len(copied.History), copied.Attachments)
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	data, err := os.ReadFile("testdata/keepass-argon2d-aes.kdbx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(data, "wrong"); !errors.Is(err, ErrIncorrectPassword) {
+		t.Errorf("wrong password: got %v", err)
+	}
+	if _, err := Read([]byte("not a database at all"), fixturePassword); !errors.Is(err, ErrNotKDBX) {
+		t.Errorf("garbage: got %v", err)
+	}
+	if _, err := Read(data[:len(data)-10], fixturePassword); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("truncated: got %v", err)
+	}
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-100] ^= 1
+	if _, err := Read(tampered, fixturePassword); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("tampered: got %v", err)
+	}
+
+	// a block claiming 4 GiB is rejected before anything is allocated for it
+	_, raw, err := readHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	huge := bytes.Clone(data)
+	lengthAt := len(raw) + 2*sha256.Size + sha256.Size
+	binary.LittleEndian.PutUint32(huge[lengthAt:], 0xFFFFFFFF)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := Read(huge, fixturePassword); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("huge block: got %v", err)
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<30 {
+		t.Errorf("huge block: allocated %d bytes", allocated)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	modified := created.Add(48 * time.Hour)
+	entry := Entry{
+		UUID: NewUUID(),
+		Tags: []string{"one", "two"},
+		Times: Times{
+			Created:  created,
+			Modified: modified,
+			Accessed: modified,
+			Expiry:   modified,
+		},
+		Strings: []String{
+			{Key: KeyTitle, Value: "Mail <&> \"quotes\""},
+			{Key: KeyUserName, Value: "ünïcødé"},
+			{Key: KeyPassword, Value: "new secret", Protected: true},
+			{Key: KeyURL, Value: "https://mail.example"},
+			{Key: KeyNotes, Value: "line one\nline two"},
+			{Key: "pin", Value: "1234", Protected: true},
+			{Key: "empty", Value: "", Protected: true},
+		},
+	}
+	previous := entry
+	previous.Tags = []string{}
+	previous.Times.Modified = created
+	previous.Strings = []String{
+		{Key: KeyTitle, Value: "Mail"},
+		{Key: KeyPassword, Value: "old secret", Protected: true},
+	}
+	entry.History = []Entry{previous}
+
+	db := &Database{
+		Name: "round trip",
+		Root: Group{
+			UUID:    NewUUID(),
+			Name:    "Root",
+			Entries: []Entry{entry},
+			Groups: []Group{{
+				UUID:   NewUUID(),
+				Name:   "Work",
+				Groups: []Group{{UUID: NewUUID(), Name: "Nested"}},
+			}},
+		},
+	}
+
+	for _, kdf := range []KDF{KDFArgon2id, KDFArgon2d, KDFAES} {
+		for _, c := range []Cipher{CipherAES256, CipherChaCha20} {
+			t.Run(kdf.String()+"-"+c.String(), func(t *testing.T) {
+				// cheap parameters, the cost doesn't change the format
+				opts := Options{KDF: kdf, Cipher: c, Iterations: 1, Memory: 1 << 20, Parallelism: 2}
+				if kdf == KDFAES {
+					opts.Iterations = 1000
+				}
+
+				var buf bytes.Buffer
+				if err := Write(&buf, db, "päss", opts); err != nil {
+					t.Fatal(err)
+				}
+				got, err := Read(buf.Bytes(), "päss")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, db) {
+					t.Errorf("round trip changed the database\ngot  %+v\nwant %+v", got, db)
+				}
+				if _, err := Read(buf.Bytes(), "pass"); !errors.Is(err, ErrIncorrectPassword) {
+					t.Errorf("wrong password: got %v", err)
+				}
+			})
+		}
+	}
+}
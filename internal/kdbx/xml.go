@@ -0,0 +1,215 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// the inner xml, only what dispass reads or needs to write a database
+// keepass and keepassxc open without complaint
+
+type xmlFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    xmlMeta  `xml:"Meta"`
+	Root    xmlRoot  `xml:"Root"`
+}
+
+type xmlMeta struct {
+	Generator         string              `xml:"Generator"`
+	DatabaseName      string              `xml:"DatabaseName"`
+	MemoryProtection  xmlMemoryProtection `xml:"MemoryProtection"`
+	RecycleBinEnabled xmlBool             `xml:"RecycleBinEnabled"`
+	RecycleBinUUID    string              `xml:"RecycleBinUUID,omitempty"`
+}
+
+type xmlMemoryProtection struct {
+	ProtectTitle    xmlBool `xml:"ProtectTitle"`
+	ProtectUserName xmlBool `xml:"ProtectUserName"`
+	ProtectPassword xmlBool `xml:"ProtectPassword"`
+	ProtectURL      xmlBool `xml:"ProtectURL"`
+	ProtectNotes    xmlBool `xml:"ProtectNotes"`
+}
+
+type xmlRoot struct {
+	Group xmlGroup `xml:"Group"`
+}
+
+type xmlGroup struct {
+	UUID       string     `xml:"UUID"`
+	Name       string     `xml:"Name"`
+	Times      xmlTimes   `xml:"Times"`
+	IsExpanded xmlBool    `xml:"IsExpanded"`
+	Entries    []xmlEntry `xml:"Entry"`
+	Groups     []xmlGroup `xml:"Group"`
+}
+
+type xmlEntry struct {
+	UUID     string         `xml:"UUID"`
+	IconID   int            `xml:"IconID"`
+	Tags     string         `xml:"Tags,omitempty"`
+	Times    xmlTimes       `xml:"Times"`
+	Strings  []xmlString    `xml:"String"`
+	Binaries []xmlBinaryRef `xml:"Binary"`
+	History  *xmlHistory    `xml:"History,omitempty"`
+}
+
+type xmlHistory struct {
+	Entries []xmlEntry `xml:"Entry"`
+}
+
+type xmlString struct {
+	Key   string   `xml:"Key"`
+	Value xmlValue `xml:"Value"`
+}
+
+type xmlValue struct {
+	Content   string  `xml:",chardata"`
+	Protected xmlBool `xml:"Protected,attr,omitempty"`
+}
+
+type xmlBinaryRef struct {
+	Key string `xml:"Key"`
+}
+
+type xmlTimes struct {
+	CreationTime         xmlTime `xml:"CreationTime"`
+	LastModificationTime xmlTime `xml:"LastModificationTime"`
+	LastAccessTime       xmlTime `xml:"LastAccessTime"`
+	ExpiryTime           xmlTime `xml:"ExpiryTime"`
+	Expires              xmlBool `xml:"Expires"`
+	UsageCount           int     `xml:"UsageCount"`
+	LocationChanged      xmlTime `xml:"LocationChanged"`
+}
+
+type xmlBool bool
+
+func (b xmlBool) MarshalText() ([]byte, error) {
+	if b {
+		return []byte("True"), nil
+	}
+	return []byte("False"), nil
+}
+
+func (b *xmlBool) UnmarshalText(text []byte) error {
+	*b = xmlBool(strings.EqualFold(strings.TrimSpace(string(text)), "true"))
+	return nil
+}
+
+// kdbx 4 times are base64 encoded seconds since year one, older files and
+// some tools use plain timestamps
+type xmlTime time.Time
+
+var yearOne = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func (t xmlTime) MarshalText() ([]byte, error) {
+	var seconds int64
+	if !time.Time(t).IsZero() {
+		seconds = time.Time(t).Unix() - yearOne.Unix()
+	}
+	raw := binary.LittleEndian.AppendUint64(nil, uint64(seconds))
+	return []byte(base64.StdEncoding.EncodeToString(raw)), nil
+}
+
+func (t *xmlTime) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+	if value == "" {
+		*t = xmlTime{}
+		return nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		*t = xmlTime(parsed)
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(raw) != 8 {
+		return errors.New("invalid time " + value)
+	}
+	seconds := int64(binary.LittleEndian.Uint64(raw))
+	if seconds <= 0 {
+		*t = xmlTime{}
+		return nil
+	}
+	*t = xmlTime(time.Unix(yearOne.Unix()+seconds, 0).UTC())
+	return nil
+}
+
+// crypt xors the content of every protected value with the inner stream,
+// going through the document in order. values are base64 in the file, so
+// protecting encodes the result and unprotecting decodes it first.
+func crypt(data []byte, stream cipher.Stream, protect bool) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var out bytes.Buffer
+	encoder := xml.NewEncoder(&out)
+
+	protected := false
+	var content []byte
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			protected = t.Name.Local == "Value" && isProtected(t)
+			content = content[:0]
+		case xml.CharData:
+			if protected {
+				content = append(content, t...)
+				continue
+			}
+		case xml.EndElement:
+			if protected {
+				value, err := cryptValue(content, stream, protect)
+				if err != nil {
+					return nil, err
+				}
+				if err := encoder.EncodeToken(xml.CharData(value)); err != nil {
+					return nil, err
+				}
+				protected = false
+			}
+		}
+		if err := encoder.EncodeToken(xml.CopyToken(token)); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func isProtected(element xml.StartElement) bool {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "Protected" && strings.EqualFold(attr.Value, "true") {
+			return true
+		}
+	}
+	return false
+}
+
+func cryptValue(content []byte, stream cipher.Stream, protect bool) ([]byte, error) {
+	if protect {
+		sealed := make([]byte, len(content))
+		stream.XORKeyStream(sealed, content)
+		return []byte(base64.StdEncoding.EncodeToString(sealed)), nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	plain := make([]byte, len(sealed))
+	stream.XORKeyStream(plain, sealed)
+	return plain, nil
+}
@@ -0,0 +1,111 @@
+package kdf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/dismint/dispass/internal/uconst"
+	"golang.org/x/crypto/argon2"
+)
+
+type ID uint8
+
+const (
+	// unsalted sha256, only kept around to open vaults from older versions
+	IDLegacySHA256 ID = iota
+	IDArgon2id
+)
+
+const (
+	KeyLen  = 32
+	SaltLen = 16
+)
+
+type Params struct {
+	ID      ID
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	Salt    []byte
+}
+
+func (id ID) String() string {
+	switch id {
+	case IDLegacySHA256:
+		return "sha256"
+	case IDArgon2id:
+		return "argon2id"
+	}
+	return fmt.Sprintf("kdf(%d)", uint8(id))
+}
+
+// New returns argon2id parameters with a fresh salt and the configured cost.
+func New() (Params, error) {
+	salt := make([]byte, SaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return Params{}, err
+	}
+	return Params{
+		ID:      IDArgon2id,
+		Time:    uconst.KDF.Time,
+		Memory:  uconst.KDF.Memory,
+		Threads: uconst.KDF.Threads,
+		Salt:    salt,
+	}, nil
+}
+
+// Legacy returns the parameters used by vaults written before salting.
+func Legacy() Params {
+	return Params{ID: IDLegacySHA256}
+}
+
+// Validate rejects parameters that are unknown or obviously unreasonable,
+// so a corrupt header can't make us allocate gigabytes of memory.
+func (p Params) Validate() error {
+	switch p.ID {
+	case IDLegacySHA256:
+		return nil
+	case IDArgon2id:
+		if p.Time == 0 || p.Threads == 0 || p.Memory < 8*uint32(p.Threads) {
+			return fmt.Errorf("invalid argon2id cost t=%d m=%d p=%d", p.Time, p.Memory, p.Threads)
+		}
+		if p.Memory > 4*1024*1024 || p.Time > 64 {
+			return fmt.Errorf("argon2id cost t=%d m=%d is too large", p.Time, p.Memory)
+		}
+		if len(p.Salt) < 8 {
+			return fmt.Errorf("argon2id salt is too short")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown kdf %v", p.ID)
+}
+
+// Outdated reports whether the parameters should be replaced by ones from New.
+func (p Params) Outdated() bool {
+	return p.ID != IDArgon2id ||
+		p.Time != uconst.KDF.Time ||
+		p.Memory != uconst.KDF.Memory ||
+		p.Threads != uconst.KDF.Threads
+}
+
+// Equal reports whether p and q derive the same key from a password.
+func (p Params) Equal(q Params) bool {
+	return p.ID == q.ID && p.Time == q.Time && p.Memory == q.Memory && p.Threads == q.Threads &&
+		bytes.Equal(p.Salt, q.Salt)
+}
+
+func (p Params) Derive(password string) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	switch p.ID {
+	case IDLegacySHA256:
+		hash := sha256.Sum256([]byte(password))
+		return hash[:], nil
+	default:
+		return argon2.IDKey([]byte(password), p.Salt, p.Time, p.Memory, p.Threads, KeyLen), nil
+	}
+}
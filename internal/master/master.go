@@ -3,10 +3,14 @@ package master
 import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dismint/dispass/internal/auditscreen"
+	"github.com/dismint/dispass/internal/backup"
 	"github.com/dismint/dispass/internal/changemaster"
 	"github.com/dismint/dispass/internal/entry"
+	"github.com/dismint/dispass/internal/importscreen"
 	"github.com/dismint/dispass/internal/interact"
 	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/trash"
 )
 
 type Model struct {
@@ -14,6 +18,10 @@ type Model struct {
 	entryModel        entry.Model
 	interactModel     interact.Model
 	changemasterModel changemaster.Model
+	backupModel       backup.Model
+	trashModel        trash.Model
+	importModel       importscreen.Model
+	auditModel        auditscreen.Model
 }
 
 func (m Model) Init() tea.Cmd {
@@ -26,6 +34,10 @@ func Initial() Model {
 		entryModel:        entry.Initial(),
 		interactModel:     interact.Initial(),
 		changemasterModel: changemaster.Initial(),
+		backupModel:       backup.Initial(),
+		trashModel:        trash.Initial(),
+		importModel:       importscreen.Initial(),
+		auditModel:        auditscreen.Initial(),
 	}
 }
 
@@ -39,15 +51,48 @@ func (m Model) screenUpdate(msg tea.Msg) (Model, tea.Cmd) {
 		cmds = append(cmds, m.interactModel.Update(msg, &m.stateModel))
 	case state.ChangeMasterScreen:
 		cmds = append(cmds, m.changemasterModel.Update(msg, &m.stateModel))
+	case state.BackupScreen:
+		cmds = append(cmds, m.backupModel.Update(msg, &m.stateModel))
+	case state.TrashScreen:
+		cmds = append(cmds, m.trashModel.Update(msg, &m.stateModel))
+	case state.ImportScreen:
+		cmds = append(cmds, m.importModel.Update(msg, &m.stateModel))
+	case state.AuditScreen:
+		cmds = append(cmds, m.auditModel.Update(msg, &m.stateModel))
 	}
 
 	return m, tea.Batch(cmds...)
 }
 
+// lock forgets everything decrypted and every input, the interact screen only
+// keeps where it was so it can return there once unlocked.
+func (m Model) lock() (Model, tea.Cmd) {
+	m.interactModel.Lock(&m.stateModel)
+	m.stateModel.Lock()
+
+	m.entryModel = entry.Initial()
+	m.entryModel.SetLocked()
+	m.changemasterModel = changemaster.Initial()
+	m.backupModel = backup.Initial()
+	m.trashModel = trash.Initial()
+	m.importModel = importscreen.Initial()
+	m.auditModel = auditscreen.Initial()
+
+	// let the entry screen pick up focus
+	m, cmd := m.screenUpdate(nil)
+	m.stateModel.Dirty = false
+
+	return m, cmd
+}
+
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	cmds := make([]tea.Cmd, 0)
 
-	m.stateModel.Update(msg)
+	if _, ok := msg.(state.LockMsg); ok {
+		return m.lock()
+	}
+
+	cmds = append(cmds, m.stateModel.Update(msg))
 	var cmd tea.Cmd
 	m, cmd = m.screenUpdate(msg)
 	cmds = append(cmds, cmd)
@@ -75,9 +120,20 @@ func (m Model) View() string {
 		view = m.interactModel.View(&m.stateModel)
 	case state.ChangeMasterScreen:
 		view = m.changemasterModel.View()
+	case state.BackupScreen:
+		view = m.backupModel.View()
+	case state.TrashScreen:
+		view = m.trashModel.View(&m.stateModel)
+	case state.ImportScreen:
+		view = m.importModel.View()
+	case state.AuditScreen:
+		view = m.auditModel.View(&m.stateModel)
 	}
 
 	view += "\n" + m.stateModel.Notification
+	if countdown := m.stateModel.ClipboardCountdown(); countdown != "" {
+		view += "\n" + countdown
+	}
 
 	return view
 }
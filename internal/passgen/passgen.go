@@ -0,0 +1,161 @@
+package passgen
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed eff_large_wordlist.txt
+var effLargeWordlist []byte
+
+var words = parseWordlist(effLargeWordlist)
+
+// Words is the wordlist passphrases are drawn from, it must not be modified.
+func Words() []string {
+	return words
+}
+
+func parseWordlist(dat []byte) []string {
+	words := make([]string, 0, 7776)
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	for scanner.Scan() {
+		// each line is the dice roll and the word, tab separated
+		_, word, _ := strings.Cut(scanner.Text(), "\t")
+		words = append(words, word)
+	}
+	return words
+}
+
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!@#$%^&*()-_=+[]{};:,.<>/?~"
+
+	// characters easily mistaken for one another in most fonts
+	ambiguousChars = "Il1|O0o"
+)
+
+type Options uconst.GeneratorSettings
+
+// DefaultOptions returns the generator settings from the config file.
+func DefaultOptions() Options {
+	return Options(uconst.Generator)
+}
+
+func randIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+func Generate(opts Options) (string, error) {
+	if opts.Passphrase {
+		return generatePassphrase(opts)
+	}
+	return generatePassword(opts)
+}
+
+func generatePassphrase(opts Options) (string, error) {
+	if opts.Words < 1 {
+		return "", errors.New("a passphrase needs at least one word")
+	}
+
+	chosen := make([]string, opts.Words)
+	for i := range chosen {
+		j, err := randIndex(len(words))
+		if err != nil {
+			return "", err
+		}
+		chosen[i] = words[j]
+		if opts.Capitalize {
+			chosen[i] = strings.ToUpper(chosen[i][:1]) + chosen[i][1:]
+		}
+	}
+	return strings.Join(chosen, opts.Separator), nil
+}
+
+func generatePassword(opts Options) (string, error) {
+	classes := make([]string, 0, 4)
+	for _, class := range []struct {
+		enabled bool
+		chars   string
+	}{
+		{opts.Lower, lowerChars},
+		{opts.Upper, upperChars},
+		{opts.Digits, digitChars},
+		{opts.Symbols, symbolChars},
+	} {
+		if !class.enabled {
+			continue
+		}
+		chars := class.chars
+		if opts.ExcludeAmbiguous {
+			chars = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(ambiguousChars, r) {
+					return -1
+				}
+				return r
+			}, chars)
+		}
+		classes = append(classes, chars)
+	}
+
+	if len(classes) == 0 {
+		return "", errors.New("at least one character class is needed")
+	}
+	if opts.MinPerClass < 0 || opts.Length < opts.MinPerClass*len(classes) {
+		return "", fmt.Errorf("a length of %d can't fit %d characters from each of %d classes",
+			opts.Length, opts.MinPerClass, len(classes))
+	}
+	if opts.Length < 1 {
+		return "", errors.New("length must be at least 1")
+	}
+
+	password := make([]byte, 0, opts.Length)
+	pick := func(chars string) error {
+		i, err := randIndex(len(chars))
+		if err != nil {
+			return err
+		}
+		password = append(password, chars[i])
+		return nil
+	}
+
+	// required characters first, the rest from everything, then shuffle so
+	// the required ones don't sit at predictable positions
+	for _, chars := range classes {
+		for range opts.MinPerClass {
+			if err := pick(chars); err != nil {
+				return "", err
+			}
+		}
+	}
+	all := strings.Join(classes, "")
+	for len(password) < opts.Length {
+		if err := pick(all); err != nil {
+			return "", err
+		}
+	}
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
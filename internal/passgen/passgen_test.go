@@ -0,0 +1,111 @@
+package passgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateClasses(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		allowed string
+	}{
+		{"all", Options{Length: 8, Lower: true, Upper: true, Digits: true, Symbols: true, MinPerClass: 2},
+			lowerChars + upperChars + digitChars + symbolChars},
+		{"digits only", Options{Length: 6, Digits: true, MinPerClass: 6}, digitChars},
+		{"no symbols", Options{Length: 20, Lower: true, Upper: true, Digits: true, MinPerClass: 1},
+			lowerChars + upperChars + digitChars},
+	}
+	required := []struct {
+		enabled func(Options) bool
+		chars   string
+	}{
+		{func(o Options) bool { return o.Lower }, lowerChars},
+		{func(o Options) bool { return o.Upper }, upperChars},
+		{func(o Options) bool { return o.Digits }, digitChars},
+		{func(o Options) bool { return o.Symbols }, symbolChars},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the minimum has to hold on every draw, not just most of them
+			for range 200 {
+				password, err := Generate(test.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(password) != test.opts.Length {
+					t.Fatalf("%q has length %d, want %d", password, len(password), test.opts.Length)
+				}
+				if i := strings.IndexFunc(password, func(r rune) bool { return !strings.ContainsRune(test.allowed, r) }); i >= 0 {
+					t.Fatalf("%q has %q from a disabled class", password, password[i])
+				}
+				for _, class := range required {
+					if !class.enabled(test.opts) {
+						continue
+					}
+					count := 0
+					for _, r := range password {
+						if strings.ContainsRune(class.chars, r) {
+							count++
+						}
+					}
+					if count < test.opts.MinPerClass {
+						t.Fatalf("%q has %d of %q, want at least %d", password, count, class.chars, test.opts.MinPerClass)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateExcludeAmbiguous(t *testing.T) {
+	opts := Options{Length: 64, Lower: true, Upper: true, Digits: true, Symbols: true, ExcludeAmbiguous: true}
+	for range 200 {
+		password, err := Generate(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i := strings.IndexAny(password, ambiguousChars); i >= 0 {
+			t.Fatalf("%q has the ambiguous %q", password, password[i])
+		}
+	}
+}
+
+func TestGenerateRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"no classes", Options{Length: 16}},
+		{"too short for the minimum", Options{Length: 3, Lower: true, Upper: true, MinPerClass: 2}},
+		{"negative minimum", Options{Length: 8, Lower: true, MinPerClass: -1}},
+		{"zero length", Options{Length: 0, Lower: true}},
+		{"negative length", Options{Length: -4, Digits: true}},
+		{"no words", Options{Passphrase: true, Words: 0}},
+	}
+	for _, test := range tests {
+		if password, err := Generate(test.opts); err == nil {
+			t.Errorf("%v: got %q, want an error", test.name, password)
+		}
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	password, err := Generate(Options{Passphrase: true, Words: 5, Separator: " ", Capitalize: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chosen := strings.Split(password, " ")
+	if len(chosen) != 5 {
+		t.Fatalf("%q has %d words, want 5", password, len(chosen))
+	}
+	for _, word := range chosen {
+		if word == "" || strings.ToUpper(word[:1]) != word[:1] {
+			t.Errorf("%q is not capitalized", word)
+		}
+	}
+	if len(Words()) != 7776 {
+		t.Errorf("wordlist has %d words, want 7776", len(Words()))
+	}
+}
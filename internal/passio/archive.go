@@ -0,0 +1,31 @@
+package passio
+
+import (
+	"github.com/dismint/dispass/internal/kdf"
+	"github.com/dismint/dispass/internal/state"
+)
+
+// EncodeArchive seals creds as a portable archive. It is a vault like any
+// other, keyed by its own passphrase with a fresh salt, so it opens on any
+// machine and never depends on the master password.
+func EncodeArchive(creds map[string]state.CredInfo, passphrase string) ([]byte, error) {
+	params, err := kdf.New()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := params.Derive(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return encodeVault(vaultPayload{Creds: creds}, params, secret)
+}
+
+// DecodeArchive opens an archive with its passphrase, which works just as
+// well on a vault or backup with its master password.
+func DecodeArchive(dat []byte, passphrase string) (map[string]state.CredInfo, error) {
+	opened, err := decodeVault(dat, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return opened.payload.Creds, nil
+}
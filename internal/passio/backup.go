@@ -0,0 +1,237 @@
+package passio
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/dismint/dispass/internal/kdf"
+	"github.com/dismint/dispass/internal/perm"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+const backupTimeLayout = "20060102T150405.000000000"
+
+type Backup struct {
+	Path string
+	Time time.Time
+}
+
+// ID names the backup by its timestamp, as it appears in the file name.
+func (b Backup) ID() string {
+	return b.Time.UTC().Format(backupTimeLayout)
+}
+
+// WriteFileAtomic replaces path with dat without ever leaving a partially
+// written file behind, a crash leaves either the old or the new contents.
+func WriteFileAtomic(path string, dat []byte, mode os.FileMode) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(dat); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// make the rename itself durable
+	if d, dirErr := os.Open(dir); dirErr == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// backupDataFile copies the current vault into the backup directory before it
+// gets overwritten, then prunes all but the newest configured backups.
+func backupDataFile() error {
+	if uconst.BackupCount <= 0 {
+		return nil
+	}
+
+	dat, err := os.ReadFile(uconst.DataFilePath)
+	if os.IsNotExist(err) || len(dat) == 0 {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(uconst.BackupDirPath, perm.DirMode); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("dp-%v.dat", time.Now().UTC().Format(backupTimeLayout))
+	if err := WriteFileAtomic(filepath.Join(uconst.BackupDirPath, name), dat, perm.FileMode); err != nil {
+		return err
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups[min(uconst.BackupCount, len(backups)):] {
+		if err := os.Remove(backup.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyring hands out the secrets for the kdf parameters of a vault, either
+// ones already known or derived from password when canDerive is set.
+type keyring struct {
+	params    []kdf.Params
+	secrets   [][]byte
+	password  string
+	canDerive bool
+}
+
+func (k *keyring) add(params kdf.Params, secret []byte) {
+	k.params = append(k.params, params)
+	k.secrets = append(k.secrets, secret)
+}
+
+func (k *keyring) secret(params kdf.Params) ([]byte, error) {
+	for i, known := range k.params {
+		if known.Equal(params) {
+			return k.secrets[i], nil
+		}
+	}
+	if !k.canDerive {
+		return nil, ErrIncorrectPassword
+	}
+	secret, err := params.Derive(k.password)
+	if err != nil {
+		return nil, err
+	}
+	// backups mostly share a salt, so each one is only derived once
+	k.add(params, secret)
+	return secret, nil
+}
+
+// rekeyBackups rewrites every backup that isn't in the current format or
+// under the current key of sm, opening it with keys. Backups that can't be
+// opened are removed, as they are still locked with an older master password.
+// It returns how many were removed.
+func rekeyBackups(sm *state.Model, keys *keyring) (int, error) {
+	backups, err := ListBackups()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, backup := range backups {
+		dat, err := os.ReadFile(backup.Path)
+		if err != nil {
+			return removed, err
+		}
+		if hdr, _, _, err := parseHeader(dat); err == nil && hdr.Version == FormatCurrent && hdr.KDF.Equal(sm.KDF) {
+			continue
+		}
+
+		opened, err := decodeVaultWith(dat, keys)
+		if errors.Is(err, ErrIncorrectPassword) || errors.Is(err, ErrCorruptHeader) {
+			log.Warnf("removing backup %v, it can't be opened with the master password", backup.Path)
+			if err := os.Remove(backup.Path); err != nil {
+				return removed, err
+			}
+			removed++
+			continue
+		} else if err != nil {
+			log.Errorf("leaving backup %v alone: %v", backup.Path, err)
+			continue
+		}
+
+		dat, err = encodeVault(opened.payload, sm.KDF, sm.Secret)
+		if err != nil {
+			return removed, err
+		}
+		if err := WriteFileAtomic(backup.Path, dat, perm.FileMode); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// ListBackups returns the available backups, newest first.
+func ListBackups() ([]Backup, error) {
+	entries, err := os.ReadDir(uconst.BackupDirPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	backups := make([]Backup, 0)
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), "dp-")
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, ".dat")
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(backupTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{
+			Path: filepath.Join(uconst.BackupDirPath, entry.Name()),
+			Time: t,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// RestoreBackup replaces the credentials in sm with those in backup, which is
+// unlocked with password. The vault keeps its current master password, and
+// the state being replaced is itself backed up first.
+func RestoreBackup(sm *state.Model, backup Backup, password string) error {
+	dat, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return err
+	}
+	if len(dat) == 0 {
+		return errors.New("backup is empty")
+	}
+
+	opened, err := decodeVault(dat, password)
+	if err != nil {
+		log.Warnf("failed to open backup %v: %v", backup.Path, err)
+		return err
+	}
+
+	sm.KeyToCredInfo = opened.payload.Creds
+	sm.Trash = opened.payload.Trash
+	sm.ForgetJournal()
+	WriteStateCreds(sm)
+	return nil
+}
@@ -0,0 +1,71 @@
+package passio
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+func TestChangeMaster(t *testing.T) {
+	useTempVault(t)
+	sm := &state.Model{KeyToCredInfo: map[string]state.CredInfo{"a": {Source: "GitHub", Password: "one"}}}
+	if err := SetMaster(sm, "old"); err != nil {
+		t.Fatal(err)
+	}
+	WriteStateCreds(sm)
+	sm.KeyToCredInfo["a"] = state.CredInfo{Source: "GitHub", Password: "two"}
+	WriteStateCreds(sm)
+
+	// left behind by a master password from before the current one
+	stranger := &state.Model{}
+	if err := SetMaster(stranger, "older"); err != nil {
+		t.Fatal(err)
+	}
+	dat, err := encodeVault(vaultPayload{}, stranger.KDF, stranger.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(uconst.BackupDirPath, "dp-20240101T000000.000000000.dat"), dat, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := ChangeMaster(sm, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed %d backups, want 1", removed)
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// changing the password itself doesn't add a backup
+	if len(backups) != 1 {
+		t.Fatalf("got backups %v", backups)
+	}
+	for _, path := range []string{uconst.DataFilePath, backups[0].Path} {
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := decodeVault(dat, "old"); !errors.Is(err, ErrIncorrectPassword) {
+			t.Errorf("%v opened with the old password: %v", filepath.Base(path), err)
+		}
+		if _, err := decodeVault(dat, "new"); err != nil {
+			t.Errorf("%v: %v", filepath.Base(path), err)
+		}
+	}
+
+	if err := RestoreBackup(sm, backups[0], "new"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sm.KeyToCredInfo["a"].Password; got != "one" {
+		t.Errorf("restored password %q, want %q", got, "one")
+	}
+}
@@ -0,0 +1,138 @@
+package passio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/dismint/dispass/internal/kdf"
+)
+
+// vault file layout, all integers big endian:
+//
+//	magic    "DISPASS\x00"
+//	version  uint16
+//	kdf      uint8 id, uint32 time, uint32 memory (KiB), uint8 threads
+//	salt     uint8 length, salt bytes
+//	cipher   uint8
+//	payload  nonce || ciphertext
+//
+// everything before the payload is passed to the cipher as additional data,
+// so tampering with the header fails decryption like a wrong password would.
+//
+// older vaults have no magic: version 1 starts with "DPK1" followed by the kdf
+// parameters, and version 0 is a bare payload keyed with sha256.
+
+type FormatVersion uint16
+
+const (
+	FormatLegacy FormatVersion = iota
+	FormatKDF
+	FormatHeader
+
+	FormatCurrent = FormatHeader
+)
+
+type CipherID uint8
+
+const (
+	CipherAES256GCM CipherID = iota + 1
+)
+
+var (
+	headerMagic    = []byte("DISPASS\x00")
+	formatKDFMagic = []byte("DPK1")
+)
+
+var (
+	ErrCorruptHeader      = errors.New("vault header is corrupt or truncated")
+	ErrUnsupportedVersion = errors.New("vault was written by a newer version of dispass")
+	ErrUnsupportedCipher  = errors.New("vault uses an unknown cipher")
+	ErrIncorrectPassword  = errors.New("incorrect password or corrupted data")
+)
+
+type header struct {
+	Version FormatVersion
+	KDF     kdf.Params
+	Cipher  CipherID
+}
+
+// marshal only produces current format headers, older ones are never written.
+func (h header) marshal() []byte {
+	buf := append([]byte{}, headerMagic...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(FormatCurrent))
+	buf = appendKDFParams(buf, h.KDF)
+	return append(buf, byte(h.Cipher))
+}
+
+func appendKDFParams(buf []byte, params kdf.Params) []byte {
+	buf = append(buf, byte(params.ID))
+	buf = binary.BigEndian.AppendUint32(buf, params.Time)
+	buf = binary.BigEndian.AppendUint32(buf, params.Memory)
+	buf = append(buf, params.Threads, byte(len(params.Salt)))
+	return append(buf, params.Salt...)
+}
+
+func readKDFParams(dat []byte) (kdf.Params, []byte, error) {
+	if len(dat) < 11 {
+		return kdf.Params{}, nil, ErrCorruptHeader
+	}
+	params := kdf.Params{
+		ID:      kdf.ID(dat[0]),
+		Time:    binary.BigEndian.Uint32(dat[1:5]),
+		Memory:  binary.BigEndian.Uint32(dat[5:9]),
+		Threads: dat[9],
+	}
+	saltLen := int(dat[10])
+	dat = dat[11:]
+	if len(dat) < saltLen {
+		return kdf.Params{}, nil, ErrCorruptHeader
+	}
+	params.Salt = append([]byte{}, dat[:saltLen]...)
+	if err := params.Validate(); err != nil {
+		return kdf.Params{}, nil, fmt.Errorf("%w: %v", ErrCorruptHeader, err)
+	}
+	return params, dat[saltLen:], nil
+}
+
+// parseHeader splits dat into its header, the raw header bytes used as
+// additional data, and the payload.
+func parseHeader(dat []byte) (header, []byte, []byte, error) {
+	switch {
+	case bytes.HasPrefix(dat, headerMagic):
+		rest := dat[len(headerMagic):]
+		if len(rest) < 2 {
+			return header{}, nil, nil, ErrCorruptHeader
+		}
+		h := header{Version: FormatVersion(binary.BigEndian.Uint16(rest))}
+		if h.Version != FormatHeader {
+			if h.Version > FormatCurrent {
+				return header{}, nil, nil, fmt.Errorf("%w (format %d)", ErrUnsupportedVersion, h.Version)
+			}
+			return header{}, nil, nil, fmt.Errorf("%w: unexpected format %d", ErrCorruptHeader, h.Version)
+		}
+		params, rest, err := readKDFParams(rest[2:])
+		if err != nil {
+			return header{}, nil, nil, err
+		}
+		if len(rest) < 1 {
+			return header{}, nil, nil, ErrCorruptHeader
+		}
+		h.KDF = params
+		h.Cipher = CipherID(rest[0])
+		if h.Cipher != CipherAES256GCM {
+			return header{}, nil, nil, fmt.Errorf("%w (%d)", ErrUnsupportedCipher, h.Cipher)
+		}
+		headerLen := len(dat) - len(rest) + 1
+		return h, dat[:headerLen], dat[headerLen:], nil
+	case bytes.HasPrefix(dat, formatKDFMagic):
+		params, rest, err := readKDFParams(dat[len(formatKDFMagic):])
+		if err != nil {
+			return header{}, nil, nil, err
+		}
+		return header{Version: FormatKDF, KDF: params, Cipher: CipherAES256GCM}, nil, rest, nil
+	default:
+		return header{Version: FormatLegacy, KDF: kdf.Legacy(), Cipher: CipherAES256GCM}, nil, dat, nil
+	}
+}
@@ -0,0 +1,193 @@
+package passio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dismint/dispass/internal/kdf"
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+// testParams is the cheapest argon2id cost Validate accepts.
+func testParams() kdf.Params {
+	return kdf.Params{ID: kdf.IDArgon2id, Time: 1, Memory: 8, Threads: 1, Salt: bytes.Repeat([]byte{7}, kdf.SaltLen)}
+}
+
+// headerWith is a current format header with version, kdf id and cipher
+// replaced, which marshal won't produce on its own.
+func headerWith(version FormatVersion, id kdf.ID, cipher CipherID) []byte {
+	params := testParams()
+	params.ID = id
+	buf := append([]byte{}, headerMagic...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(version))
+	buf = appendKDFParams(buf, params)
+	return append(buf, byte(cipher))
+}
+
+func TestParseHeader(t *testing.T) {
+	current := header{Version: FormatCurrent, KDF: testParams(), Cipher: CipherAES256GCM}.marshal()
+	formatKDF := appendKDFParams(append([]byte{}, formatKDFMagic...), testParams())
+	tooCostly := testParams()
+	tooCostly.Memory = 1 << 30
+
+	tests := []struct {
+		name    string
+		dat     []byte
+		version FormatVersion
+		kdf     kdf.ID
+		err     error
+	}{
+		{"current", append(current, "payload"...), FormatHeader, kdf.IDArgon2id, nil},
+		{"current without payload", current, FormatHeader, kdf.IDArgon2id, nil},
+		{"kdf", append(formatKDF, "payload"...), FormatKDF, kdf.IDArgon2id, nil},
+		{"legacy", []byte("payload"), FormatLegacy, kdf.IDLegacySHA256, nil},
+		{"empty legacy", nil, FormatLegacy, kdf.IDLegacySHA256, nil},
+		{"magic only", headerMagic, 0, 0, ErrCorruptHeader},
+		{"half a version", append(append([]byte{}, headerMagic...), 0), 0, 0, ErrCorruptHeader},
+		{"no kdf", current[:len(headerMagic)+2], 0, 0, ErrCorruptHeader},
+		{"short salt", current[:len(current)-4], 0, 0, ErrCorruptHeader},
+		{"no cipher", current[:len(current)-1], 0, 0, ErrCorruptHeader},
+		{"short kdf", formatKDF[:len(formatKDF)-1], 0, 0, ErrCorruptHeader},
+		{"newer version", headerWith(FormatCurrent+1, kdf.IDArgon2id, CipherAES256GCM), 0, 0, ErrUnsupportedVersion},
+		{"older version behind magic", headerWith(FormatKDF, kdf.IDArgon2id, CipherAES256GCM), 0, 0, ErrCorruptHeader},
+		{"unknown kdf", headerWith(FormatCurrent, 9, CipherAES256GCM), 0, 0, ErrCorruptHeader},
+		{"unreasonable cost", appendKDFParams(append([]byte{}, formatKDFMagic...), tooCostly), 0, 0, ErrCorruptHeader},
+		{"unknown cipher", headerWith(FormatCurrent, kdf.IDArgon2id, 9), 0, 0, ErrUnsupportedCipher},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, additionalData, payload, err := parseHeader(test.dat)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("got error %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Version != test.version || h.KDF.ID != test.kdf || h.Cipher != CipherAES256GCM {
+				t.Errorf("got version %d kdf %v cipher %d", h.Version, h.KDF.ID, h.Cipher)
+			}
+			// only the current format authenticates its header
+			if h.Version == FormatHeader && !bytes.Equal(additionalData, current) {
+				t.Errorf("additional data: got %x, want %x", additionalData, current)
+			} else if h.Version != FormatHeader && additionalData != nil {
+				t.Errorf("additional data: got %x for format %d", additionalData, h.Version)
+			}
+			if want := bytes.TrimPrefix(bytes.TrimPrefix(test.dat, current), formatKDF); !bytes.Equal(payload, want) {
+				t.Errorf("payload: got %q, want %q", payload, want)
+			}
+		})
+	}
+}
+
+// useTempVault points the vault and its backups at a fresh directory.
+func useTempVault(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	uconst.DataFilePath = filepath.Join(dir, "dp.dat")
+	uconst.BackupDirPath = filepath.Join(dir, "backups")
+	uconst.BackupCount = 5
+	uconst.KDF = uconst.KDFPreset{Time: 1, Memory: 8, Threads: 1}
+}
+
+func TestReadStateCredsMigrates(t *testing.T) {
+	creds := map[string]state.CredInfo{
+		"a": {Source: "GitHub", Username: "alice", Password: "hunter2"},
+	}
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(creds); err != nil {
+		t.Fatal(err)
+	}
+	legacyKey := sha256.Sum256([]byte("pw"))
+	kdfKey, err := testParams().Derive("pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		prefix []byte
+		key    []byte
+	}{
+		{"legacy", nil, legacyKey[:]},
+		{"kdf", appendKDFParams(append([]byte{}, formatKDFMagic...), testParams()), kdfKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTempVault(t)
+			ciphertext, err := Encrypt(test.key, payload.Bytes(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(uconst.DataFilePath, append(test.prefix, ciphertext...), 0600); err != nil {
+				t.Fatal(err)
+			}
+			// a backup in the old format, which has to be brought along
+			if err := os.MkdirAll(uconst.BackupDirPath, 0700); err != nil {
+				t.Fatal(err)
+			}
+			oldBackup := filepath.Join(uconst.BackupDirPath, "dp-20240101T000000.000000000.dat")
+			if err := os.WriteFile(oldBackup, append(test.prefix, ciphertext...), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			sm := &state.Model{}
+			if err := ReadStateCreds(sm, "wrong"); !errors.Is(err, ErrIncorrectPassword) {
+				t.Fatalf("wrong password: got %v", err)
+			}
+			if err := ReadStateCreds(sm, "pw"); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(sm.KeyToCredInfo, creds) {
+				t.Errorf("got %v, want %v", sm.KeyToCredInfo, creds)
+			}
+
+			dat, err := os.ReadFile(uconst.DataFilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h, _, _, err := parseHeader(dat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Version != FormatCurrent || h.KDF.ID != kdf.IDArgon2id {
+				t.Errorf("rewritten as format %d with %v", h.Version, h.KDF.ID)
+			}
+			opened, err := decodeVault(dat, "pw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(opened.payload.Creds, creds) {
+				t.Errorf("reopened: got %v, want %v", opened.payload.Creds, creds)
+			}
+
+			// the old file isn't backed up, and the old backup is rewritten
+			backups, err := ListBackups()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(backups) != 1 || backups[0].Path != oldBackup {
+				t.Fatalf("got backups %v", backups)
+			}
+			if dat, err = os.ReadFile(oldBackup); err != nil {
+				t.Fatal(err)
+			}
+			if h, _, _, err := parseHeader(dat); err != nil || h.Version != FormatCurrent || h.KDF.ID != kdf.IDArgon2id {
+				t.Errorf("backup rewritten as format %d with %v: %v", h.Version, h.KDF.ID, err)
+			}
+			if _, err := decodeVault(dat, "pw"); err != nil {
+				t.Errorf("backup: %v", err)
+			}
+		})
+	}
+}
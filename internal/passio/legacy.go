@@ -0,0 +1,61 @@
+package passio
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+)
+
+// removeLegacyIndex shreds the on-disk index older versions kept in the
+// working directory, it held every credential in plaintext. Only a directory
+// bleve wrote is touched, anything else called index is left alone.
+func removeLegacyIndex(dir string) {
+	for _, marker := range []string{"index_meta.json", "store"} {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err != nil {
+			return
+		}
+	}
+
+	log.Infof("removing legacy plaintext index %v", dir)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		return shred(path)
+	})
+	if err != nil {
+		log.Errorf("failed to shred legacy index: %v", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Errorf("failed to remove legacy index: %v", err)
+	}
+}
+
+// shred overwrites a file with zeros before it is removed. this is best
+// effort, journaling and copy-on-write filesystems may keep old blocks around.
+func shred(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(f, zeroReader{}, info.Size()); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,107 @@
+package passio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/charmbracelet/log"
+	"github.com/dismint/dispass/internal/perm"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+// MigrateLegacyFiles moves a vault, its backups and the log that older
+// versions left in the working directory to their configured locations. It
+// never overwrites anything, so it only ever happens once.
+func MigrateLegacyFiles() error {
+	if _, err := os.Stat(uconst.LegacyDataFileName); err == nil {
+		if err := migrate(uconst.LegacyDataFileName, uconst.DataFilePath); err != nil {
+			return err
+		}
+		if _, err := os.Stat(uconst.LegacyBackupDirName); err == nil {
+			if err := migrate(uconst.LegacyBackupDirName, uconst.BackupDirPath); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := os.Stat(uconst.LegacyLogFileName); err == nil {
+		if err := migrate(uconst.LegacyLogFileName, uconst.LogFilePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrate(from, to string) error {
+	fromAbs, err := filepath.Abs(from)
+	if err != nil {
+		return err
+	}
+	if fromAbs == to {
+		// the configured location is the working directory
+		return nil
+	}
+	if _, err := os.Stat(to); err == nil {
+		log.Warnf("not migrating %v, %v already exists", fromAbs, to)
+		return nil
+	}
+
+	log.Infof("migrating %v to %v", fromAbs, to)
+	if err := os.MkdirAll(filepath.Dir(to), perm.DirMode); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); errors.Is(err, syscall.EXDEV) {
+		if err := moveAcrossDevices(from, to); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to move %v: %w", from, err)
+	}
+
+	// older versions created everything world readable
+	problems, err := perm.Check(to)
+	if err != nil {
+		return err
+	}
+	return perm.Fix(problems)
+}
+
+// moveAcrossDevices copies then removes, for when rename can't be used.
+func moveAcrossDevices(from, to string) error {
+	info, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		if err := os.MkdirAll(to, perm.DirMode); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(from)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := moveAcrossDevices(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return os.Remove(from)
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dat, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(to, dat, perm.FileMode); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
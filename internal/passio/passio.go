@@ -5,23 +5,59 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/gob"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/dismint/dispass/internal/kdf"
+	"github.com/dismint/dispass/internal/perm"
 	"github.com/dismint/dispass/internal/state"
 	"github.com/dismint/dispass/internal/uconst"
 )
 
-func SecretFromString(password string) []byte {
-	hash := sha256.Sum256([]byte(password))
-	return hash[:]
+// vaultPayload is what gets gob encoded and encrypted, fields can be added
+// without a format bump since gob leaves missing ones zeroed
+type vaultPayload struct {
+	Creds map[string]state.CredInfo
+	Trash map[string]state.TrashedCredInfo
 }
 
-func Encrypt(key, plaintext []byte) ([]byte, error) {
+// ChangeMaster switches the vault over to a new master password. The backups
+// are re-encrypted under it too, since they would otherwise keep opening with
+// the old one, and those that can't be are removed. It returns how many were
+// removed.
+func ChangeMaster(sm *state.Model, password string) (int, error) {
+	keys := &keyring{}
+	keys.add(sm.KDF, sm.Secret)
+	if err := SetMaster(sm, password); err != nil {
+		return 0, err
+	}
+	// backing up the vault under the old password defeats the point
+	writeState(sm, false)
+	return rekeyBackups(sm, keys)
+}
+
+// SetMaster derives a fresh secret for password with a new salt and the
+// configured cost, it does not write anything to disk.
+func SetMaster(sm *state.Model, password string) error {
+	params, err := kdf.New()
+	if err != nil {
+		return err
+	}
+	secret, err := params.Derive(password)
+	if err != nil {
+		return err
+	}
+	sm.KDF = params
+	sm.Secret = secret
+	return nil
+}
+
+func Encrypt(key, plaintext, additionalData []byte) ([]byte, error) {
 	// create aes block
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -40,11 +76,11 @@ func Encrypt(key, plaintext []byte) ([]byte, error) {
 	}
 
 	// encrypt and prepend nonce to ciphertext
-	ciphertext := aesgcm.Seal(nonce, nonce, plaintext, nil)
+	ciphertext := aesgcm.Seal(nonce, nonce, plaintext, additionalData)
 	return ciphertext, nil
 }
 
-func Decrypt(key, ciphertext []byte) ([]byte, error) {
+func Decrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -61,49 +97,160 @@ func Decrypt(key, ciphertext []byte) ([]byte, error) {
 	}
 
 	nonce, ct := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plaintext, err := aesgcm.Open(nil, nonce, ct, nil)
+	plaintext, err := aesgcm.Open(nil, nonce, ct, additionalData)
 	if err != nil {
-		return nil, errors.New("incorrect password or corrupted data")
+		return nil, ErrIncorrectPassword
 	}
 
 	return plaintext, nil
 }
 
-func WriteStateCreds(sm *state.Model) {
+// encodeVault serializes creds into a current format vault keyed by secret.
+func encodeVault(payload vaultPayload, params kdf.Params, secret []byte) ([]byte, error) {
 	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
+	if err := gob.NewEncoder(&buf).Encode(payload); err != nil {
+		return nil, fmt.Errorf("failed to encode: %w", err)
+	}
+
+	hdr := header{Version: FormatCurrent, KDF: params, Cipher: CipherAES256GCM}.marshal()
+	ciphertext, err := Encrypt(secret, buf.Bytes(), hdr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	return append(hdr, ciphertext...), nil
+}
+
+type openedVault struct {
+	header  header
+	secret  []byte
+	payload vaultPayload
+}
+
+// decodeVault parses and decrypts a vault of any known format version.
+func decodeVault(dat []byte, password string) (openedVault, error) {
+	return decodeVaultWith(dat, &keyring{password: password, canDerive: true})
+}
+
+// decodeVaultWith is decodeVault with the secret looked up in keys.
+func decodeVaultWith(dat []byte, keys *keyring) (openedVault, error) {
+	hdr, additionalData, ciphertext, err := parseHeader(dat)
+	if err != nil {
+		return openedVault{}, err
+	}
+	secret, err := keys.secret(hdr.KDF)
+	if err != nil {
+		return openedVault{}, err
+	}
 
-	if err := enc.Encode(sm.KeyToCredInfo); err != nil {
-		log.Fatalf("failed to encode: %v", err)
+	opened := openedVault{
+		header: hdr,
+		secret: secret,
+		payload: vaultPayload{
+			Creds: make(map[string]state.CredInfo),
+			Trash: make(map[string]state.TrashedCredInfo),
+		},
+	}
+	// an empty legacy file is an empty vault
+	if len(ciphertext) == 0 && hdr.Version == FormatLegacy {
+		return opened, nil
 	}
 
-	bytes, err := Encrypt(sm.Secret, buf.Bytes())
+	plaintext, err := Decrypt(secret, ciphertext, additionalData)
 	if err != nil {
-		log.Fatalf("failed to encrypt: %v", err)
+		return openedVault{}, err
 	}
 
-	if err := os.WriteFile(uconst.DataFileName, bytes, 0644); err != nil {
-		log.Fatalf("failed to write to %v: %v", uconst.DataFileName, err)
+	dec := gob.NewDecoder(bytes.NewBuffer(plaintext))
+	switch hdr.Version {
+	case FormatLegacy, FormatKDF:
+		err = dec.Decode(&opened.payload.Creds)
+	default:
+		err = dec.Decode(&opened.payload)
+	}
+	if err != nil {
+		return openedVault{}, fmt.Errorf("failed to decode vault: %w", err)
 	}
+	if opened.payload.Creds == nil {
+		opened.payload.Creds = make(map[string]state.CredInfo)
+	}
+	if opened.payload.Trash == nil {
+		opened.payload.Trash = make(map[string]state.TrashedCredInfo)
+	}
+
+	return opened, nil
 }
 
-func ReadStateCreds(sm *state.Model) error {
-	dat, err := os.ReadFile(uconst.DataFileName)
+func WriteStateCreds(sm *state.Model) {
+	writeState(sm, true)
+}
+
+// WriteStateUsage writes the vault without taking a backup first, for changes
+// to usage metadata only, so simply reading entries doesn't rotate out the
+// backups of real changes.
+func WriteStateUsage(sm *state.Model) {
+	writeState(sm, false)
+}
+
+func writeState(sm *state.Model, backup bool) {
+	payload := vaultPayload{Creds: sm.KeyToCredInfo, Trash: sm.Trash}
+	dat, err := encodeVault(payload, sm.KDF, sm.Secret)
 	if err != nil {
-		log.Fatalf("failed to write to %v: %v", uconst.DataFileName, err)
+		log.Fatalf("failed to write vault: %v", err)
 	}
 
-	if len(dat) > 0 {
-		d, err := Decrypt(sm.Secret, dat)
-		if err != nil {
-			log.Warnf("failed to decrypt: %v", err)
-			return err
+	if backup {
+		if err := backupDataFile(); err != nil {
+			// not worth losing the write over, the previous vault is still intact
+			log.Errorf("failed to back up %v: %v", uconst.DataFilePath, err)
 		}
-		buf := bytes.NewBuffer(d)
-		dec := gob.NewDecoder(buf)
-		if err = dec.Decode(&sm.KeyToCredInfo); err != nil {
-			log.Fatalf("failed to decode: %v", err)
+	}
+	if err := WriteFileAtomic(uconst.DataFilePath, dat, perm.FileMode); err != nil {
+		log.Fatalf("failed to write to %v: %v", uconst.DataFilePath, err)
+	}
+}
+
+// ReadStateCreds unlocks the vault with password, filling in the secret, kdf
+// parameters and credentials of sm. Vaults in an older format or using an
+// outdated key derivation are rewritten in the current one.
+func ReadStateCreds(sm *state.Model, password string) error {
+	dat, err := os.ReadFile(uconst.DataFilePath)
+	if err != nil {
+		log.Fatalf("failed to read %v: %v", uconst.DataFilePath, err)
+	}
+
+	opened, err := decodeVault(dat, password)
+	if err != nil {
+		log.Warnf("failed to open vault: %v", err)
+		return err
+	}
+
+	sm.KeyToCredInfo = opened.payload.Creds
+	sm.Trash = opened.payload.Trash
+	sm.KDF = opened.header.KDF
+	sm.Secret = opened.secret
+	removeLegacyIndex(uconst.LegacyBleveDirName)
+	purged := sm.PurgeExpiredTrash(time.Now())
+
+	if opened.header.KDF.Outdated() {
+		log.Infof("upgrading vault key derivation from %v", opened.header.KDF.ID)
+		if err := SetMaster(sm, password); err != nil {
+			log.Fatalf("failed to derive key: %v", err)
+		}
+	}
+	if opened.header.Version != FormatCurrent || opened.header.KDF.Outdated() {
+		log.Infof("migrating vault from format %d to %d", opened.header.Version, FormatCurrent)
+		// the old file is only as strong as its format, so it isn't kept as a
+		// backup and the existing backups are brought up to date as well
+		writeState(sm, false)
+		keys := &keyring{password: password, canDerive: true}
+		keys.add(opened.header.KDF, opened.secret)
+		if _, err := rekeyBackups(sm, keys); err != nil {
+			log.Errorf("failed to re-encrypt backups: %v", err)
 		}
+	} else if purged {
+		log.Infof("purging expired entries from the trash")
+		WriteStateCreds(sm)
 	}
 
 	return nil
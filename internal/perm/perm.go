@@ -0,0 +1,104 @@
+package perm
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	FileMode fs.FileMode = 0600
+	DirMode  fs.FileMode = 0700
+)
+
+type Problem struct {
+	Path string
+	Mode fs.FileMode
+	// Foreign is set when the path is owned by someone else, which chmod
+	// can't fix
+	Foreign bool
+}
+
+// Exposed reports whether group or others can access the path.
+func (p Problem) Exposed() bool {
+	return p.Mode.Perm()&0077 != 0
+}
+
+func (p Problem) Want() fs.FileMode {
+	if p.Mode.IsDir() {
+		return DirMode
+	}
+	return FileMode
+}
+
+func (p Problem) Fixable() bool {
+	return !p.Foreign
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.Foreign && p.Exposed():
+		return fmt.Sprintf("%v is owned by another user and has mode %v, expected %v", p.Path, p.Mode.Perm(), p.Want().Perm())
+	case p.Foreign:
+		return fmt.Sprintf("%v is owned by another user", p.Path)
+	}
+	return fmt.Sprintf("%v has mode %v, expected %v", p.Path, p.Mode.Perm(), p.Want().Perm())
+}
+
+// Check reports every existing path, or anything below it for directories,
+// that is accessible to group or others or owned by another user.
+func Check(paths ...string) ([]Problem, error) {
+	return check(paths, true)
+}
+
+// CheckDirs is Check for the directories themselves, leaving out what they
+// contain.
+func CheckDirs(dirs ...string) ([]Problem, error) {
+	return check(dirs, false)
+}
+
+func check(paths []string, recurse bool) ([]Problem, error) {
+	problems := make([]Problem, 0)
+	if !supported {
+		return problems, nil
+	}
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			problem := Problem{Path: path, Mode: info.Mode(), Foreign: foreign(info)}
+			if problem.Foreign || problem.Exposed() {
+				problems = append(problems, problem)
+			}
+			if !recurse && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+// Fix tightens the mode of every fixable problem.
+func Fix(problems []Problem) error {
+	for _, problem := range problems {
+		if !problem.Fixable() {
+			continue
+		}
+		if err := os.Chmod(problem.Path, problem.Want()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package perm
+
+import "io/fs"
+
+// unix permission bits and ownership don't map onto this platform, so
+// nothing is checked
+const supported = false
+
+func foreign(info fs.FileInfo) bool {
+	return false
+}
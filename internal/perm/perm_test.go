@@ -0,0 +1,79 @@
+//go:build unix
+
+package perm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	private := filepath.Join(dir, "private")
+	readable := filepath.Join(dir, "readable")
+	for path, mode := range map[string]os.FileMode{private: 0600, readable: 0644} {
+		if err := os.WriteFile(path, nil, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems, err := Check(dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, problem := range problems {
+		paths = append(paths, problem.Path)
+	}
+	if want := []string{dir, readable}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Check: got %v, want %v", paths, want)
+	}
+
+	// only the directory itself
+	dirs, err := CheckDirs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || dirs[0].Path != dir || dirs[0].Want() != DirMode {
+		t.Errorf("CheckDirs: got %v", dirs)
+	}
+
+	if err := Fix(problems); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := Check(dir); err != nil || len(problems) != 0 {
+		t.Errorf("after Fix: got %v, %v", problems, err)
+	}
+}
+
+func TestCheckForeign(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foreign")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(path, os.Geteuid()+1, -1); err != nil {
+		t.Skipf("can't hand a file to another user: %v", err)
+	}
+
+	problems, err := Check(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || !problems[0].Foreign || !problems[0].Exposed() || problems[0].Fixable() {
+		t.Fatalf("got %+v", problems)
+	}
+	if want := path + " is owned by another user and has mode -rw-r--r--, expected -rw-------"; problems[0].String() != want {
+		t.Errorf("got %q, want %q", problems[0].String(), want)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package perm
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+const supported = true
+
+func foreign(info fs.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) != os.Geteuid()
+}
@@ -0,0 +1,128 @@
+package state
+
+import "reflect"
+
+// journalDepth caps how many changes can be undone
+const journalDepth = 100
+
+// entrySnapshot is an entry as it was at one point, either side may be nil
+// when it was not there
+type entrySnapshot struct {
+	cred    *CredInfo
+	trashed *TrashedCredInfo
+}
+
+// Snapshot captures some entries before they change, see Record
+type Snapshot map[string]entrySnapshot
+
+// JournalEntry is a change that can be undone and redone
+type JournalEntry struct {
+	Description string
+	before      Snapshot
+	after       Snapshot
+}
+
+// IDs are the entries the change touched.
+func (e JournalEntry) IDs() []string {
+	ids := make([]string, 0, len(e.before))
+	for id := range e.before {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// journal holds the changes made this session, it is lost on lock
+type journal struct {
+	undo []JournalEntry
+	redo []JournalEntry
+}
+
+// Snapshot captures the entries with ids as they are now.
+func (m *Model) Snapshot(ids ...string) Snapshot {
+	snapshot := make(Snapshot, len(ids))
+	for _, id := range ids {
+		var entry entrySnapshot
+		if ci, exists := m.KeyToCredInfo[id]; exists {
+			entry.cred = &ci
+		}
+		if trashed, exists := m.Trash[id]; exists {
+			entry.trashed = &trashed
+		}
+		snapshot[id] = entry
+	}
+	return snapshot
+}
+
+// Record journals the change from before to the current state of the same
+// entries. Anything that could have been redone is dropped.
+func (m *Model) Record(description string, before Snapshot) {
+	ids := make([]string, 0, len(before))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	after := m.Snapshot(ids...)
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	m.journal.undo = append(m.journal.undo, JournalEntry{
+		Description: description,
+		before:      before,
+		after:       after,
+	})
+	if len(m.journal.undo) > journalDepth {
+		m.journal.undo = m.journal.undo[1:]
+	}
+	m.journal.redo = nil
+}
+
+// ForgetJournal drops every change, for when the entries were replaced
+// wholesale and the journal no longer applies.
+func (m *Model) ForgetJournal() {
+	m.journal = journal{}
+}
+
+// Undo reverts the last recorded change. The caller is left to bring the
+// index and the vault on disk up to date with the entries it touched.
+func (m *Model) Undo() (JournalEntry, bool) {
+	if len(m.journal.undo) == 0 {
+		return JournalEntry{}, false
+	}
+	entry := m.journal.undo[len(m.journal.undo)-1]
+	m.journal.undo = m.journal.undo[:len(m.journal.undo)-1]
+	m.journal.redo = append(m.journal.redo, entry)
+	m.applySnapshot(entry.before)
+	return entry, true
+}
+
+// Redo applies the last undone change again, see Undo.
+func (m *Model) Redo() (JournalEntry, bool) {
+	if len(m.journal.redo) == 0 {
+		return JournalEntry{}, false
+	}
+	entry := m.journal.redo[len(m.journal.redo)-1]
+	m.journal.redo = m.journal.redo[:len(m.journal.redo)-1]
+	m.journal.undo = append(m.journal.undo, entry)
+	m.applySnapshot(entry.after)
+	return entry, true
+}
+
+// applySnapshot puts entries back the way they were, except for when and how
+// often they were used, which no undo should roll back.
+func (m *Model) applySnapshot(snapshot Snapshot) {
+	for id, entry := range snapshot {
+		used := m.KeyToCredInfo[id]
+		delete(m.KeyToCredInfo, id)
+		delete(m.Trash, id)
+		if entry.cred != nil {
+			ci := *entry.cred
+			if used.LastUsed.After(ci.LastUsed) {
+				ci.LastUsed = used.LastUsed
+			}
+			ci.UseCount = max(ci.UseCount, used.UseCount)
+			m.KeyToCredInfo[id] = ci
+		}
+		if entry.trashed != nil {
+			m.Trash[id] = *entry.trashed
+		}
+	}
+}
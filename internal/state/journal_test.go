@@ -0,0 +1,113 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newJournalModel() *Model {
+	return &Model{
+		KeyToCredInfo: map[string]CredInfo{"a": {Source: "GitHub", Password: "one"}},
+		Trash:         make(map[string]TrashedCredInfo),
+	}
+}
+
+// setPassword changes the password of id the way the editor does.
+func setPassword(m *Model, id, password string) {
+	before := m.Snapshot(id)
+	ci := m.KeyToCredInfo[id]
+	ci.Password = password
+	m.KeyToCredInfo[id] = ci
+	m.Record("Edit "+ci.Source, before)
+}
+
+func TestUndoRedo(t *testing.T) {
+	m := newJournalModel()
+	setPassword(m, "a", "two")
+
+	entry, ok := m.Undo()
+	if !ok || entry.Description != "Edit GitHub" {
+		t.Fatalf("undo: got %q, %v", entry.Description, ok)
+	}
+	if got := m.KeyToCredInfo["a"].Password; got != "one" {
+		t.Errorf("after undo: got %q, want %q", got, "one")
+	}
+	if _, ok := m.Undo(); ok {
+		t.Errorf("undo with nothing left to undo")
+	}
+
+	if _, ok := m.Redo(); !ok {
+		t.Fatal("redo: nothing to redo")
+	}
+	if got := m.KeyToCredInfo["a"].Password; got != "two" {
+		t.Errorf("after redo: got %q, want %q", got, "two")
+	}
+	if _, ok := m.Redo(); ok {
+		t.Errorf("redo with nothing left to redo")
+	}
+}
+
+func TestUndoTrash(t *testing.T) {
+	m := newJournalModel()
+	before := m.Snapshot("a")
+	m.TrashCred("a", time.Now())
+	m.Record("Delete GitHub", before)
+
+	m.Undo()
+	if _, exists := m.KeyToCredInfo["a"]; !exists || len(m.Trash) != 0 {
+		t.Errorf("after undo: entries %v, trash %v", m.KeyToCredInfo, m.Trash)
+	}
+	m.Redo()
+	if _, exists := m.Trash["a"]; !exists || len(m.KeyToCredInfo) != 0 {
+		t.Errorf("after redo: entries %v, trash %v", m.KeyToCredInfo, m.Trash)
+	}
+}
+
+func TestNewEditClearsRedo(t *testing.T) {
+	m := newJournalModel()
+	setPassword(m, "a", "two")
+	m.Undo()
+	setPassword(m, "a", "three")
+
+	if _, ok := m.Redo(); ok {
+		t.Errorf("redo after a new edit")
+	}
+	if got := m.KeyToCredInfo["a"].Password; got != "three" {
+		t.Errorf("got %q, want %q", got, "three")
+	}
+	m.Undo()
+	if got := m.KeyToCredInfo["a"].Password; got != "one" {
+		t.Errorf("after undo: got %q, want %q", got, "one")
+	}
+}
+
+func TestUnchangedIsNotRecorded(t *testing.T) {
+	m := newJournalModel()
+	setPassword(m, "a", "one")
+	if _, ok := m.Undo(); ok {
+		t.Errorf("undo of a change that changed nothing")
+	}
+}
+
+func TestJournalDepth(t *testing.T) {
+	m := newJournalModel()
+	for i := range journalDepth + 5 {
+		setPassword(m, "a", fmt.Sprint(i))
+	}
+
+	undone := 0
+	for {
+		if _, ok := m.Undo(); !ok {
+			break
+		}
+		undone++
+	}
+	if undone != journalDepth {
+		t.Errorf("undid %d changes, want %d", undone, journalDepth)
+	}
+	// the oldest changes fell off the end
+	if got, want := m.KeyToCredInfo["a"].Password, fmt.Sprint(4); got != want {
+		t.Errorf("after undoing everything: got %q, want %q", got, want)
+	}
+}
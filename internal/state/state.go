@@ -1,11 +1,17 @@
 package state
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/blevesearch/bleve"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/dismint/dispass/internal/clip"
+	"github.com/dismint/dispass/internal/kdf"
 	"github.com/dismint/dispass/internal/uconst"
 )
 
@@ -15,6 +21,10 @@ const (
 	EntryScreen Screen = iota
 	InteractScreen
 	ChangeMasterScreen
+	BackupScreen
+	TrashScreen
+	ImportScreen
+	AuditScreen
 )
 
 type MessageLevel int
@@ -25,31 +35,225 @@ const (
 	MessageLevelNotif
 )
 
+type FieldType int
+
+const (
+	FieldText FieldType = iota
+	FieldHidden
+	FieldURL
+)
+
+func (t FieldType) String() string {
+	switch t {
+	case FieldHidden:
+		return "hidden"
+	case FieldURL:
+		return "url"
+	default:
+		return "text"
+	}
+}
+
+// CustomField holds anything that doesn't fit the fixed fields, like security
+// questions or recovery codes
+type CustomField struct {
+	Name  string
+	Type  FieldType
+	Value string
+}
+
+// PreviousPassword is a password an entry had until Replaced
+type PreviousPassword struct {
+	Password string
+	Replaced time.Time
+}
+
+// CredInfo is stored in the vault with gob, so fields can be added freely but
+// never renamed. Zero timestamps mean the entry predates them being tracked.
 type CredInfo struct {
 	Source   string
 	Username string
 	Password string
+	TOTP     string
+	URLs     []string
+	Notes    string
+	Tags     []string
+	Fields   []CustomField
+	// History is newest first and kept to uconst.HistoryDepth
+	History []PreviousPassword
+
+	Created  time.Time
+	Modified time.Time
+	LastUsed time.Time
+	// UseCount is how often the entry was copied from, entries used before
+	// it was counted have LastUsed set but no count
+	UseCount int
+
+	// Breach is what the last breach check found for the current password
+	Breach Breach
+}
+
+// PasswordChanged is when the password was last set, zero if that was before
+// it was tracked. Imported history isn't always newest first, so all of it is
+// looked at.
+func (ci CredInfo) PasswordChanged() time.Time {
+	if len(ci.History) == 0 {
+		return ci.Created
+	}
+	changed := ci.History[0].Replaced
+	for _, previous := range ci.History[1:] {
+		if previous.Replaced.After(changed) {
+			changed = previous.Replaced
+		}
+	}
+	return changed
+}
+
+// Breach is a password looked up in a corpus of breached password hashes.
+type Breach struct {
+	Checked time.Time
+	// Count is how often the password was seen, zero if never
+	Count int64
+}
+
+// SortedIDs orders entries by source, then id so the order is stable.
+func SortedIDs(creds map[string]CredInfo) []string {
+	ids := make([]string, 0, len(creds))
+	for id := range creds {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int {
+		if c := strings.Compare(strings.ToLower(creds[a].Source), strings.ToLower(creds[b].Source)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return ids
+}
+
+// NewCredInfo stamps a freshly created entry.
+func NewCredInfo(ci CredInfo, now time.Time) CredInfo {
+	ci.Created = now
+	ci.Modified = now
+	ci.LastUsed = time.Time{}
+	ci.UseCount = 0
+	return ci
+}
+
+// Used returns ci with a use at now recorded, which ranks it higher in
+// searches.
+func (ci CredInfo) Used(now time.Time) CredInfo {
+	ci.LastUsed = now
+	ci.UseCount++
+	return ci
+}
+
+// Edited returns ci with the editable fields replaced by those of edit,
+// keeping the timestamps and bumping Modified if anything changed. A changed
+// password pushes the old one onto the history.
+func (ci CredInfo) Edited(edit CredInfo, now time.Time) CredInfo {
+	edit.Created = ci.Created
+	edit.Modified = ci.Modified
+	edit.LastUsed = ci.LastUsed
+	edit.UseCount = ci.UseCount
+	edit.History = ci.History
+	edit.Breach = ci.Breach
+	if !ci.sameContent(edit) {
+		edit.Modified = now
+	}
+	if edit.Password != ci.Password && ci.Password != "" {
+		previous := PreviousPassword{Password: ci.Password, Replaced: now}
+		edit.History = append([]PreviousPassword{previous}, ci.History...)
+	}
+	if edit.Password != ci.Password {
+		// the check was for the old password
+		edit.Breach = Breach{}
+	}
+	if len(edit.History) > uconst.HistoryDepth {
+		edit.History = edit.History[:uconst.HistoryDepth]
+	}
+	return edit
+}
+
+// RestoredPassword returns ci with the i-th previous password made current
+// again, the current one taking its place in the history.
+func (ci CredInfo) RestoredPassword(i int, now time.Time) CredInfo {
+	edit := ci
+	edit.Password = ci.History[i].Password
+	ci.History = slices.Delete(slices.Clone(ci.History), i, i+1)
+	return ci.Edited(edit, now)
+}
+
+func (ci CredInfo) sameContent(other CredInfo) bool {
+	return ci.Source == other.Source &&
+		ci.Username == other.Username &&
+		ci.Password == other.Password &&
+		ci.TOTP == other.TOTP &&
+		ci.Notes == other.Notes &&
+		slices.Equal(ci.URLs, other.URLs) &&
+		slices.Equal(ci.Tags, other.Tags) &&
+		slices.Equal(ci.Fields, other.Fields)
+}
+
+// TrashedCredInfo is a deleted entry waiting out uconst.TrashRetention
+type TrashedCredInfo struct {
+	CredInfo
+	Deleted time.Time
 }
 
 type ShowNotificationMsg string
-type ClearNotificationMsg struct{}
+
+// ClearNotificationMsg only clears the notification it was sent for, a newer
+// one stays up for its own duration
+type ClearNotificationMsg struct {
+	notification string
+}
+
+type clipboardTickMsg struct {
+	generation int
+}
+
+type idleTickMsg struct {
+	generation int
+}
+
+// LockMsg asks for the vault to be locked, everything decrypted has to go
+type LockMsg struct{}
+
+// CredsReloadedMsg is sent after KeyToCredInfo was replaced wholesale, so
+// anything derived from it needs to be rebuilt
+type CredsReloadedMsg struct{}
+
+// SelectEntryMsg asks the interact screen to show the entry, clearing the
+// search so it is among the results
+type SelectEntryMsg struct {
+	ID string
+}
 
 func NotificationMsg(message string, messageLevel MessageLevel) tea.Cmd {
+	return NotificationMsgFor(message, messageLevel, 1*time.Second)
+}
+
+// NotificationMsgFor is NotificationMsg for messages that need to stay up
+// longer, such as those offering an undo.
+func NotificationMsgFor(message string, messageLevel MessageLevel, duration time.Duration) tea.Cmd {
+	var messageStyle lipgloss.Style
+	switch messageLevel {
+	case MessageLevelError:
+		messageStyle = uconst.MessageLevelErrorStyle
+	case MessageLevelSuccess:
+		messageStyle = uconst.MessageLevelSuccessStyle
+	case MessageLevelNotif:
+		messageStyle = uconst.MessageLevelNotifStyle
+	}
+	notification := messageStyle.Render(message)
+
 	return tea.Batch(
 		func() tea.Msg {
-			var messageStyle lipgloss.Style
-			switch messageLevel {
-			case MessageLevelError:
-				messageStyle = uconst.MessageLevelErrorStyle
-			case MessageLevelSuccess:
-				messageStyle = uconst.MessageLevelSuccessStyle
-			case MessageLevelNotif:
-				messageStyle = uconst.MessageLevelNotifStyle
-			}
-			return ShowNotificationMsg(messageStyle.Render(message))
+			return ShowNotificationMsg(notification)
 		},
-		tea.Tick(1*time.Second, func(time.Time) tea.Msg {
-			return ClearNotificationMsg{}
+		tea.Tick(duration, func(time.Time) tea.Msg {
+			return ClearNotificationMsg{notification: notification}
 		}),
 	)
 }
@@ -57,11 +261,21 @@ func NotificationMsg(message string, messageLevel MessageLevel) tea.Cmd {
 type Model struct {
 	Screen        Screen
 	KeyToCredInfo map[string]CredInfo
+	Trash         map[string]TrashedCredInfo
 	Secret        []byte
+	KDF           kdf.Params
 	Index         bleve.Index
 	Notification  string
 	Quitting      bool
 
+	clipboardSum        clip.Sum
+	clipboardDeadline   time.Time
+	clipboardGeneration int
+
+	idleGeneration int
+
+	journal journal
+
 	Dirty bool
 }
 
@@ -69,7 +283,9 @@ func Initial() Model {
 	return Model{
 		Screen:        EntryScreen,
 		KeyToCredInfo: make(map[string]CredInfo),
+		Trash:         make(map[string]TrashedCredInfo),
 		// Secret
+		// KDF
 		// Index
 		// Notification
 		// Quitting
@@ -78,11 +294,130 @@ func Initial() Model {
 	}
 }
 
-func (m *Model) Update(msg tea.Msg) {
+// TrashCred moves an entry into the trash, keeping its id.
+func (m *Model) TrashCred(id string, now time.Time) {
+	m.Trash[id] = TrashedCredInfo{CredInfo: m.KeyToCredInfo[id], Deleted: now}
+	delete(m.KeyToCredInfo, id)
+}
+
+// RestoreCred moves an entry out of the trash again.
+func (m *Model) RestoreCred(id string) (CredInfo, bool) {
+	trashed, exists := m.Trash[id]
+	if !exists {
+		return CredInfo{}, false
+	}
+	m.KeyToCredInfo[id] = trashed.CredInfo
+	delete(m.Trash, id)
+	return trashed.CredInfo, true
+}
+
+// PurgeExpiredTrash drops entries trashed longer ago than the retention
+// period, reporting whether there were any.
+func (m *Model) PurgeExpiredTrash(now time.Time) bool {
+	if uconst.TrashRetention <= 0 {
+		return false
+	}
+	purged := false
+	for id, trashed := range m.Trash {
+		if now.Sub(trashed.Deleted) > uconst.TrashRetention {
+			delete(m.Trash, id)
+			purged = true
+		}
+	}
+	return purged
+}
+
+// CopyToClipboard copies value and starts counting down to when it gets
+// cleared from the clipboard again.
+func (m *Model) CopyToClipboard(value string) (tea.Cmd, error) {
+	sum, err := clip.Copy(value)
+	if err != nil {
+		return nil, err
+	}
+	if uconst.ClipboardClearAfter <= 0 {
+		return nil, nil
+	}
+
+	m.clipboardSum = sum
+	m.clipboardDeadline = time.Now().Add(uconst.ClipboardClearAfter)
+	m.clipboardGeneration++
+	return clipboardTick(m.clipboardGeneration), nil
+}
+
+func clipboardTick(generation int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return clipboardTickMsg{generation: generation}
+	})
+}
+
+// ClipboardCountdown describes when the clipboard gets cleared, if pending.
+func (m *Model) ClipboardCountdown() string {
+	if m.clipboardDeadline.IsZero() {
+		return ""
+	}
+	remaining := time.Until(m.clipboardDeadline).Round(time.Second)
+	return uconst.MessageLevelNotifStyle.Render(
+		fmt.Sprintf("Clipboard clears in %v", remaining),
+	)
+}
+
+// Lock wipes the secret and every credential, returning to the entry screen.
+func (m *Model) Lock() {
+	clear(m.Secret)
+	m.Secret = nil
+	m.KDF = kdf.Params{}
+	m.KeyToCredInfo = make(map[string]CredInfo)
+	m.Trash = make(map[string]TrashedCredInfo)
+	m.journal = journal{}
+	if m.Index != nil {
+		m.Index.Close()
+		m.Index = nil
+	}
+	m.Screen = EntryScreen
+	m.Dirty = true
+}
+
+func (m *Model) Update(msg tea.Msg) tea.Cmd {
 	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if uconst.IdleLockAfter <= 0 {
+			return nil
+		}
+		// every key press restarts the countdown, earlier ticks are ignored
+		m.idleGeneration++
+		generation := m.idleGeneration
+		return tea.Tick(uconst.IdleLockAfter, func(time.Time) tea.Msg {
+			return idleTickMsg{generation: generation}
+		})
+	case idleTickMsg:
+		if msg.generation != m.idleGeneration || m.Screen == EntryScreen {
+			return nil
+		}
+		return func() tea.Msg { return LockMsg{} }
 	case ShowNotificationMsg:
 		m.Notification = string(msg)
 	case ClearNotificationMsg:
-		m.Notification = ""
+		if msg.notification == m.Notification {
+			m.Notification = ""
+		}
+	case clipboardTickMsg:
+		// a newer copy restarted the countdown
+		if msg.generation != m.clipboardGeneration || m.clipboardDeadline.IsZero() {
+			return nil
+		}
+		if time.Now().Before(m.clipboardDeadline) {
+			return clipboardTick(msg.generation)
+		}
+		m.clipboardDeadline = time.Time{}
+		// the helper process does the same, this covers it failing to start
+		cleared, err := clip.ClearIfUnchanged(m.clipboardSum)
+		if err != nil {
+			log.Errorf("failed to clear clipboard: %v", err)
+		}
+		if !cleared {
+			return nil
+		}
+		return NotificationMsg("Clipboard Cleared", MessageLevelNotif)
 	}
+	return nil
 }
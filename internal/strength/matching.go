@@ -0,0 +1,514 @@
+package strength
+
+import (
+	_ "embed"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dismint/dispass/internal/passgen"
+)
+
+type Pattern int
+
+const (
+	PatternBruteforce Pattern = iota
+	PatternDictionary
+	PatternSpatial
+	PatternSequence
+	PatternRepeat
+	PatternDate
+)
+
+func (p Pattern) String() string {
+	switch p {
+	case PatternDictionary:
+		return "dictionary"
+	case PatternSpatial:
+		return "spatial"
+	case PatternSequence:
+		return "sequence"
+	case PatternRepeat:
+		return "repeat"
+	case PatternDate:
+		return "date"
+	default:
+		return "bruteforce"
+	}
+}
+
+// Match is a part of the password an attacker would guess as a whole, the
+// runes from I to J inclusive.
+type Match struct {
+	Pattern Pattern
+	I, J    int
+	Token   string
+	Guesses float64
+
+	// Dictionary is which list a dictionary match came from
+	Dictionary Dictionary
+	Rank       int
+	Reversed   bool
+	L33t       bool
+	// Turns is how often a keyboard pattern changes direction
+	Turns int
+	// Base is what a repeat repeats
+	Base string
+}
+
+type Dictionary int
+
+const (
+	DictionaryPasswords Dictionary = iota
+	DictionaryWords
+	DictionaryUserInputs
+)
+
+// ordered by how often people pick them
+//
+//go:embed common_passwords.txt
+var commonPasswords string
+
+var (
+	passwordRanks = rankedList(strings.Fields(commonPasswords))
+	// the wordlist is alphabetical rather than by frequency, so every word
+	// counts as middling
+	wordRanks = flatList(passgen.Words(), len(passgen.Words())/2)
+)
+
+func rankedList(list []string) map[string]int {
+	ranks := make(map[string]int, len(list))
+	for i, item := range list {
+		item = strings.ToLower(item)
+		if _, exists := ranks[item]; !exists {
+			ranks[item] = i + 1
+		}
+	}
+	return ranks
+}
+
+func flatList(list []string, rank int) map[string]int {
+	ranks := make(map[string]int, len(list))
+	for _, item := range list {
+		ranks[strings.ToLower(item)] = rank
+	}
+	return ranks
+}
+
+// minimum token lengths, shorter ones are no better than guessing
+const (
+	minDictionaryLength = 3
+	minPatternLength    = 3
+)
+
+func omnimatch(rs []rune, userInputs map[string]int) []Match {
+	matches := make([]Match, 0)
+	matches = append(matches, dictionaryMatches(rs, userInputs)...)
+	matches = append(matches, spatialMatches(rs)...)
+	matches = append(matches, sequenceMatches(rs)...)
+	matches = append(matches, repeatMatches(rs, userInputs)...)
+	matches = append(matches, dateMatches(rs)...)
+	return matches
+}
+
+// l33t substitutions, the second table covers the letters some characters
+// could stand for as well
+var (
+	l33tTable = map[rune]rune{
+		'4': 'a', '@': 'a', '8': 'b', '(': 'c', '{': 'c', '[': 'c', '<': 'c',
+		'3': 'e', '6': 'g', '9': 'g', '1': 'i', '!': 'i', '|': 'i', '0': 'o',
+		'$': 's', '5': 's', '7': 't', '+': 't', '2': 'z', '%': 'x',
+	}
+	l33tAlternatives = map[rune]rune{'1': 'l', '|': 'l', '7': 'l'}
+)
+
+// unl33t undoes substitutions, returning how many distinct characters were
+// substituted.
+func unl33t(token string, alternative bool) (string, int) {
+	substituted := make(map[rune]bool)
+	var b strings.Builder
+	for _, r := range token {
+		sub, ok := l33tTable[r]
+		if alt, altOK := l33tAlternatives[r]; alternative && altOK {
+			sub, ok = alt, true
+		}
+		if ok {
+			substituted[r] = true
+			r = sub
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), len(substituted)
+}
+
+func dictionaryMatches(rs []rune, userInputs map[string]int) []Match {
+	dictionaries := []map[string]int{
+		DictionaryPasswords:  passwordRanks,
+		DictionaryWords:      wordRanks,
+		DictionaryUserInputs: userInputs,
+	}
+	lookup := func(word string) (Dictionary, int, bool) {
+		for d, ranks := range dictionaries {
+			if rank, ok := ranks[word]; ok {
+				return Dictionary(d), rank, true
+			}
+		}
+		return 0, 0, false
+	}
+
+	matches := make([]Match, 0)
+	for i := range rs {
+		for j := i + minDictionaryLength - 1; j < len(rs); j++ {
+			token := string(rs[i : j+1])
+			lower := strings.ToLower(token)
+			match := Match{Pattern: PatternDictionary, I: i, J: j, Token: token}
+
+			if d, rank, ok := lookup(lower); ok {
+				match.Dictionary, match.Rank = d, rank
+				matches = append(matches, withDictionaryGuesses(match, 0))
+			}
+			if reversed := reverse(lower); reversed != lower {
+				if d, rank, ok := lookup(reversed); ok {
+					match.Dictionary, match.Rank, match.Reversed = d, rank, true
+					matches = append(matches, withDictionaryGuesses(match, 0))
+				}
+			}
+			for _, alternative := range []bool{false, true} {
+				plain, subs := unl33t(lower, alternative)
+				if subs == 0 {
+					continue
+				}
+				if d, rank, ok := lookup(plain); ok {
+					match.Dictionary, match.Rank, match.Reversed, match.L33t = d, rank, false, true
+					matches = append(matches, withDictionaryGuesses(match, subs))
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func withDictionaryGuesses(m Match, subs int) Match {
+	m.Guesses = float64(m.Rank) * uppercaseVariations(m.Token) * math.Pow(2, float64(subs))
+	if m.Reversed {
+		m.Guesses *= 2
+	}
+	return m
+}
+
+// uppercaseVariations counts the ways the capitals could have been placed,
+// with a capital first or everything in capitals being the usual choices.
+func uppercaseVariations(token string) float64 {
+	var upper, lower int
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	rs := []rune(token)
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0, upper == 1 && (unicode.IsUpper(rs[0]) || unicode.IsUpper(rs[len(rs)-1])):
+		return 2
+	}
+	variations := 0.0
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// qwerty rows, with the columns of the number row moved one left so every
+// row lines up the same way with the one below
+var (
+	keyboardRows        = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+	keyboardShiftedRows = []string{"~!@#$%^&*()_+", "QWERTYUIOP{}|", "ASDFGHJKL:\"", "ZXCVBNM<>?"}
+)
+
+type keyPosition struct {
+	row, col int
+	shifted  bool
+}
+
+var keyboard = func() map[rune]keyPosition {
+	positions := make(map[rune]keyPosition)
+	for row := range keyboardRows {
+		offset := 0
+		if row == 0 {
+			offset = -1
+		}
+		for col, r := range []rune(keyboardRows[row]) {
+			positions[r] = keyPosition{row: row, col: col + offset}
+		}
+		for col, r := range []rune(keyboardShiftedRows[row]) {
+			positions[r] = keyPosition{row: row, col: col + offset, shifted: true}
+		}
+	}
+	return positions
+}()
+
+// neighbours of a key as row and column steps, each one is a direction
+var keyboardDirections = [][2]int{{0, -1}, {0, 1}, {-1, 0}, {-1, 1}, {1, -1}, {1, 0}}
+
+const (
+	keyboardStartingPositions = 47
+	keyboardAverageDegree     = 4.6
+)
+
+func keyDirection(from, to rune) (int, bool) {
+	a, okA := keyboard[from]
+	b, okB := keyboard[to]
+	if !okA || !okB {
+		return 0, false
+	}
+	for direction, step := range keyboardDirections {
+		if b.row-a.row == step[0] && b.col-a.col == step[1] {
+			return direction, true
+		}
+	}
+	return 0, false
+}
+
+func spatialMatches(rs []rune) []Match {
+	matches := make([]Match, 0)
+	for i := 0; i < len(rs)-1; {
+		j, turns, lastDirection := i, 0, -1
+		for j+1 < len(rs) {
+			direction, ok := keyDirection(rs[j], rs[j+1])
+			if !ok {
+				break
+			}
+			if direction != lastDirection {
+				turns++
+				lastDirection = direction
+			}
+			j++
+		}
+		if j-i+1 >= minPatternLength {
+			token := string(rs[i : j+1])
+			matches = append(matches, Match{
+				Pattern: PatternSpatial, I: i, J: j, Token: token,
+				Turns: turns, Guesses: spatialGuesses(rs[i:j+1], turns),
+			})
+		}
+		i = max(j, i+1)
+	}
+	return matches
+}
+
+func spatialGuesses(token []rune, turns int) float64 {
+	guesses := 0.0
+	for i := 2; i <= len(token); i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * keyboardStartingPositions * math.Pow(keyboardAverageDegree, float64(j))
+		}
+	}
+	var shifted, unshifted int
+	for _, r := range token {
+		if keyboard[r].shifted {
+			shifted++
+		} else {
+			unshifted++
+		}
+	}
+	if shifted > 0 && unshifted == 0 {
+		guesses *= 2
+	} else if shifted > 0 {
+		variations := 0.0
+		for i := 1; i <= min(shifted, unshifted); i++ {
+			variations += binomial(shifted+unshifted, i)
+		}
+		guesses *= variations
+	}
+	return guesses
+}
+
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsLower(r):
+		return 1
+	case unicode.IsUpper(r):
+		return 2
+	case unicode.IsDigit(r):
+		return 3
+	}
+	return 0
+}
+
+func sequenceMatches(rs []rune) []Match {
+	matches := make([]Match, 0)
+	for i := 0; i < len(rs)-1; {
+		delta := rs[i+1] - rs[i]
+		j := i + 1
+		if runeClass(rs[i]) != 0 && runeClass(rs[i]) == runeClass(rs[j]) && delta != 0 && abs(int(delta)) <= 5 {
+			for j+1 < len(rs) && rs[j+1]-rs[j] == delta && runeClass(rs[j+1]) == runeClass(rs[i]) {
+				j++
+			}
+		}
+		if j-i+1 >= minPatternLength {
+			token := string(rs[i : j+1])
+			base := 26.0
+			switch {
+			case strings.ContainsRune("aAzZ019", rs[i]):
+				base = 4
+			case unicode.IsDigit(rs[i]):
+				base = 10
+			}
+			guesses := base * float64(j-i+1)
+			if delta < 0 {
+				guesses *= 2
+			}
+			matches = append(matches, Match{Pattern: PatternSequence, I: i, J: j, Token: token, Guesses: guesses})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+// repeatMatches finds the longest run of some base repeated from each
+// position, which is guessed as the base and a count.
+func repeatMatches(rs []rune, userInputs map[string]int) []Match {
+	matches := make([]Match, 0)
+	for i := 0; i < len(rs); {
+		bestLength, bestBase := 0, 0
+		for base := 1; i+2*base <= len(rs); base++ {
+			reps := 1
+			for i+(reps+1)*base <= len(rs) && string(rs[i+reps*base:i+(reps+1)*base]) == string(rs[i:i+base]) {
+				reps++
+			}
+			if reps >= 2 && reps*base > bestLength {
+				bestLength, bestBase = reps*base, base
+			}
+		}
+		if bestLength < minPatternLength {
+			i++
+			continue
+		}
+		base := string(rs[i : i+bestBase])
+		matches = append(matches, Match{
+			Pattern: PatternRepeat, I: i, J: i + bestLength - 1, Token: string(rs[i : i+bestLength]),
+			Base:    base,
+			Guesses: estimate([]rune(base), userInputs).Guesses * float64(bestLength/bestBase),
+		})
+		i += bestLength
+	}
+	return matches
+}
+
+const referenceYear = 2000
+
+var (
+	separatedDate = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+	// where digits can be split into day, month and year
+	dateSplits = map[int][][2]int{
+		4: {{1, 2}, {2, 3}},
+		5: {{1, 3}, {2, 3}},
+		6: {{1, 2}, {2, 4}, {4, 5}},
+		7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+		8: {{2, 4}, {4, 6}},
+	}
+)
+
+func dateMatches(rs []rune) []Match {
+	matches := make([]Match, 0)
+	for i := range rs {
+		for j := i + 3; j < len(rs) && j-i < 10; j++ {
+			token := string(rs[i : j+1])
+			if year, ok := dateFromToken(token); ok {
+				guesses := 365 * math.Max(math.Abs(float64(year-referenceYear)), 20)
+				if !isDigits(token) {
+					guesses *= 4
+				}
+				matches = append(matches, Match{Pattern: PatternDate, I: i, J: j, Token: token, Guesses: guesses})
+			}
+			if len(token) == 4 && isDigits(token) {
+				if year, _ := strconv.Atoi(token); year >= 1900 && year <= 2099 {
+					guesses := math.Max(math.Abs(float64(year-referenceYear)), 20)
+					matches = append(matches, Match{Pattern: PatternDate, I: i, J: j, Token: token, Guesses: guesses})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// dateFromToken reads a day, month and year in any order people write them,
+// returning the year.
+func dateFromToken(token string) (int, bool) {
+	var candidates [][3]int
+	if parts := separatedDate.FindStringSubmatch(token); parts != nil {
+		if parts[2] != parts[4] {
+			return 0, false
+		}
+		a, _ := strconv.Atoi(parts[1])
+		b, _ := strconv.Atoi(parts[3])
+		c, _ := strconv.Atoi(parts[5])
+		candidates = append(candidates, [3]int{a, b, c})
+	} else if isDigits(token) {
+		for _, split := range dateSplits[len(token)] {
+			a, _ := strconv.Atoi(token[:split[0]])
+			b, _ := strconv.Atoi(token[split[0]:split[1]])
+			c, _ := strconv.Atoi(token[split[1]:])
+			candidates = append(candidates, [3]int{a, b, c})
+		}
+	}
+
+	for _, c := range candidates {
+		for _, order := range [][3]int{{0, 1, 2}, {2, 1, 0}, {2, 0, 1}, {1, 0, 2}} {
+			year, month, day := c[order[0]], c[order[1]], c[order[2]]
+			if year < 100 {
+				year += 1900
+				if year < 1950 {
+					year += 100
+				}
+			}
+			if year >= 1000 && year <= 2050 && month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+				return year, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func reverse(s string) string {
+	rs := []rune(s)
+	for i, j := 0, len(rs)-1; i < j; i, j = i+1, j-1 {
+		rs[i], rs[j] = rs[j], rs[i]
+	}
+	return string(rs)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
@@ -0,0 +1,235 @@
+// Package strength estimates how many guesses a password would take, by
+// finding the cheapest way to build it from common passwords, words,
+// keyboard patterns, sequences, repeats and dates.
+package strength
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxLength bounds the work, anything past it is counted as random
+const maxLength = 100
+
+// guesses for a run of characters no pattern explains, and the least any
+// pattern can cost
+const (
+	bruteforceCardinality = 10
+	minSingleGuesses      = 10
+	minMultiGuesses       = 50
+)
+
+// Result is the estimate for a password.
+type Result struct {
+	Guesses float64
+	// Score runs from 0, guessed almost at once, to 4, out of reach
+	Score int
+	// Sequence is the cheapest way found to build the password
+	Sequence []Match
+}
+
+// Check estimates password. userInputs are words an attacker would try first
+// for this entry, such as its source and username.
+func Check(password string, userInputs ...string) Result {
+	inputs := make(map[string]int, len(userInputs))
+	rank := 1
+	for _, input := range userInputs {
+		for _, word := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+		}) {
+			if _, exists := inputs[word]; !exists {
+				inputs[word] = rank
+				rank++
+			}
+		}
+	}
+
+	rs := []rune(password)
+	extra := 0
+	if len(rs) > maxLength {
+		rs, extra = rs[:maxLength], len(rs)-maxLength
+	}
+	result := estimate(rs, inputs)
+	result.Guesses *= math.Pow(bruteforceCardinality, float64(extra))
+	result.Score = score(result.Guesses)
+	return result
+}
+
+// estimate finds the sequence of matches covering rs with the fewest
+// guesses, where each position is reached either by a match ending there or
+// by one more random character.
+func estimate(rs []rune, userInputs map[string]int) Result {
+	if len(rs) == 0 {
+		return Result{Guesses: 1}
+	}
+	matchesByEnd := make([][]Match, len(rs))
+	for _, m := range omnimatch(rs, userInputs) {
+		matchesByEnd[m.J] = append(matchesByEnd[m.J], m)
+	}
+
+	best := make([]float64, len(rs)+1)
+	via := make([]*Match, len(rs)+1)
+	best[0] = 1
+	for k := 1; k <= len(rs); k++ {
+		best[k] = best[k-1] * bruteforceCardinality
+		via[k] = nil
+		for i := range matchesByEnd[k-1] {
+			m := &matchesByEnd[k-1][i]
+			guesses := m.Guesses
+			if m.I == m.J {
+				guesses = math.Max(guesses, minSingleGuesses)
+			} else {
+				guesses = math.Max(guesses, minMultiGuesses)
+			}
+			if candidate := best[m.I] * guesses; candidate < best[k] {
+				best[k], via[k] = candidate, m
+			}
+		}
+	}
+
+	// walk back, joining neighbouring random characters into one match
+	sequence := make([]Match, 0)
+	for k := len(rs); k > 0; {
+		if via[k] != nil {
+			sequence = append(sequence, *via[k])
+			k = via[k].I
+			continue
+		}
+		start := k - 1
+		for start > 0 && via[start] == nil {
+			start--
+		}
+		sequence = append(sequence, Match{
+			Pattern: PatternBruteforce, I: start, J: k - 1, Token: string(rs[start:k]),
+			Guesses: math.Pow(bruteforceCardinality, float64(k-start)),
+		})
+		k = start
+	}
+	for i, j := 0, len(sequence)-1; i < j; i, j = i+1, j-1 {
+		sequence[i], sequence[j] = sequence[j], sequence[i]
+	}
+	return Result{Guesses: best[len(rs)], Sequence: sequence}
+}
+
+func score(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	}
+	return 4
+}
+
+var labels = []string{"very weak", "weak", "fair", "good", "strong"}
+
+// Label names the score.
+func (r Result) Label() string {
+	return labels[r.Score]
+}
+
+// guessesPerSecond assumes an offline attack against a slow hash
+const guessesPerSecond = 1e4
+
+// CrackTime is how long the guesses would take.
+func (r Result) CrackTime() float64 {
+	return r.Guesses / guessesPerSecond
+}
+
+// CrackTimeDisplay is CrackTime in words, such as "3 hours".
+func (r Result) CrackTimeDisplay() string {
+	seconds := r.CrackTime()
+	units := []struct {
+		name    string
+		seconds float64
+	}{
+		{"second", 1},
+		{"minute", 60},
+		{"hour", 60 * 60},
+		{"day", 24 * 60 * 60},
+		{"month", 31 * 24 * 60 * 60},
+		{"year", 365 * 24 * 60 * 60},
+	}
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds >= 100*units[len(units)-1].seconds:
+		return "centuries"
+	}
+	unit := units[0]
+	for _, u := range units {
+		if seconds >= u.seconds {
+			unit = u
+		}
+	}
+	n := int(math.Round(seconds / unit.seconds))
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit.name)
+	}
+	return fmt.Sprintf("%d %ss", n, unit.name)
+}
+
+// Warning explains what makes a weak password weak, and is empty for a good
+// one.
+func (r Result) Warning() string {
+	if r.Score >= 3 {
+		return ""
+	}
+	if len(r.Sequence) == 0 {
+		return "No password"
+	}
+
+	// the longest pattern is what the password leans on most
+	var longest *Match
+	for i := range r.Sequence {
+		m := &r.Sequence[i]
+		if m.Pattern != PatternBruteforce && (longest == nil || m.J-m.I > longest.J-longest.I) {
+			longest = m
+		}
+	}
+	if longest == nil {
+		return "Short passwords are easy to guess, add more characters"
+	}
+
+	switch longest.Pattern {
+	case PatternDictionary:
+		switch {
+		case longest.Dictionary == DictionaryUserInputs:
+			return "Passwords based on the entry's name or username are easy to guess"
+		case longest.L33t:
+			return "Predictable substitutions like '@' instead of 'a' don't help much"
+		case longest.Reversed:
+			return "Reversed words aren't much harder to guess"
+		case longest.Dictionary == DictionaryPasswords && longest.Rank <= 10:
+			return "This is a top-10 common password"
+		case longest.Dictionary == DictionaryPasswords && longest.Rank <= 100:
+			return "This is a top-100 common password"
+		case longest.Dictionary == DictionaryPasswords:
+			return "This is a very common password"
+		case len(r.Sequence) == 1:
+			return "A single word is easy to guess"
+		}
+		return "Common words are easy to guess"
+	case PatternSpatial:
+		if longest.Turns == 1 {
+			return "Straight rows of keys are easy to guess"
+		}
+		return "Short keyboard patterns are easy to guess"
+	case PatternRepeat:
+		if len([]rune(longest.Base)) == 1 {
+			return `Repeats like "aaa" are easy to guess`
+		}
+		return `Repeats like "abcabc" are only slightly harder to guess than "abc"`
+	case PatternSequence:
+		return "Sequences like abc or 6543 are easy to guess"
+	case PatternDate:
+		return "Dates and years are easy to guess"
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package strength
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		password string
+		pattern  Pattern
+		score    int
+		warning  string
+	}{
+		{"", PatternBruteforce, 0, "No password"},
+		{"password", PatternDictionary, 0, "This is a top-10 common password"},
+		{"zxcvbnm", PatternDictionary, 0, "This is a top-100 common password"},
+		{"qwerty123", PatternDictionary, 0, "This is a very common password"},
+		{"P@ssw0rd", PatternDictionary, 0, "Predictable substitutions like '@' instead of 'a' don't help much"},
+		{"drowssap", PatternDictionary, 0, "Reversed words aren't much harder to guess"},
+		{"staple1987", PatternDictionary, 1, "Common words are easy to guess"},
+		{"mju7ytgb", PatternSpatial, 2, "Short keyboard patterns are easy to guess"},
+		{"abcdefgh", PatternSequence, 0, "Sequences like abc or 6543 are easy to guess"},
+		{"987654", PatternSequence, 0, "Sequences like abc or 6543 are easy to guess"},
+		{"aaaaaaaa", PatternRepeat, 0, `Repeats like "aaa" are easy to guess`},
+		{"abababababab", PatternRepeat, 0, `Repeats like "abcabc" are only slightly harder to guess than "abc"`},
+		{"p4ssw0rdp4ssw0rd", PatternRepeat, 0, `Repeats like "abcabc" are only slightly harder to guess than "abc"`},
+		{"1987", PatternDate, 0, "Dates and years are easy to guess"},
+		{"19/04/1987", PatternDate, 1, "Dates and years are easy to guess"},
+		{"31121999", PatternDate, 1, "Dates and years are easy to guess"},
+		{"GitHub2024", PatternDictionary, 1, "Passwords based on the entry's name or username are easy to guess"},
+		{"alice123", PatternDictionary, 1, "Passwords based on the entry's name or username are easy to guess"},
+		{"correcthorsebatterystaple", PatternDictionary, 4, ""},
+		{"x7#Kq9!vLm2$Wp4z", PatternBruteforce, 4, ""},
+	}
+	for _, test := range tests {
+		result := Check(test.password, "GitHub", "alice")
+		if result.Score != test.score || result.Warning() != test.warning {
+			t.Errorf("Check(%q) = %d %q, want %d %q", test.password, result.Score, result.Warning(), test.score, test.warning)
+		}
+		if test.password != "" && result.Sequence[0].Pattern != test.pattern {
+			t.Errorf("Check(%q) starts with a %v match, want %v", test.password, result.Sequence[0].Pattern, test.pattern)
+		}
+	}
+}
+
+func TestUserInputs(t *testing.T) {
+	with := Check("GitHub2024", "GitHub", "alice")
+	without := Check("GitHub2024")
+	if with.Guesses >= without.Guesses {
+		t.Errorf("the source should make it easier to guess, %v guesses with it and %v without", with.Guesses, without.Guesses)
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		guesses float64
+		score   int
+	}{
+		{1, 0},
+		{1e3, 0},
+		{1e3 + 10, 1},
+		{1e6, 1},
+		{1e6 + 10, 2},
+		{1e8 + 10, 3},
+		{1e10, 3},
+		{1e10 + 10, 4},
+		{1e20, 4},
+	}
+	for _, test := range tests {
+		if got := score(test.guesses); got != test.score {
+			t.Errorf("score(%g) = %d, want %d", test.guesses, got, test.score)
+		}
+	}
+}
+
+func TestCrackTimeDisplay(t *testing.T) {
+	tests := []struct {
+		guesses float64
+		want    string
+	}{
+		{50, "less than a second"},
+		{1e4, "1 second"},
+		{3e4, "3 seconds"},
+		{60e4, "1 minute"},
+		{3 * 3600e4, "3 hours"},
+		{2 * 86400e4, "2 days"},
+		{40 * 86400e4, "1 month"},
+		{5 * 365 * 86400e4, "5 years"},
+		{100 * 365 * 86400e4, "centuries"},
+	}
+	for _, test := range tests {
+		if got := (Result{Guesses: test.guesses}).CrackTimeDisplay(); got != test.want {
+			t.Errorf("%g guesses: got %q, want %q", test.guesses, got, test.want)
+		}
+	}
+}
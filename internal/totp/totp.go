@@ -0,0 +1,211 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Algorithm int
+
+const (
+	SHA1 Algorithm = iota
+	SHA256
+	SHA512
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case SHA256:
+		return "SHA256"
+	case SHA512:
+		return "SHA512"
+	default:
+		return "SHA1"
+	}
+}
+
+func (a Algorithm) hash() func() hash.Hash {
+	switch a {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
+
+const (
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+	MinDigits     = 6
+	MaxDigits     = 8
+
+	// a day, anything longer is a typo and would only risk overflowing
+	maxPeriod = 24 * 60 * 60
+)
+
+var ErrEmptySecret = errors.New("empty secret")
+
+// Key is everything needed to generate codes, as found in an otpauth URI
+type Key struct {
+	Secret    []byte
+	Algorithm Algorithm
+	Digits    int
+	Period    time.Duration
+	Issuer    string
+	Account   string
+}
+
+// Parse reads either a bare base32 secret, using the usual SHA1, 6 digits
+// and 30 second period, or an otpauth://totp/ URI.
+func Parse(s string) (Key, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(strings.ToLower(s), "otpauth:") {
+		return parseURI(s)
+	}
+
+	secret, err := decodeSecret(s)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{
+		Secret:    secret,
+		Algorithm: SHA1,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}, nil
+}
+
+func parseURI(s string) (Key, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Key{}, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+	if !strings.EqualFold(u.Host, "totp") {
+		return Key{}, fmt.Errorf("unsupported otp type %q, only totp is supported", u.Host)
+	}
+
+	query := u.Query()
+	secret, err := decodeSecret(query.Get("secret"))
+	if err != nil {
+		return Key{}, err
+	}
+	key := Key{
+		Secret:    secret,
+		Algorithm: SHA1,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+		Issuer:    query.Get("issuer"),
+	}
+
+	// the label is "issuer:account" or just "account"
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, found := strings.Cut(label, ":"); found {
+		key.Account = strings.TrimSpace(account)
+		if key.Issuer == "" {
+			key.Issuer = issuer
+		}
+	} else {
+		key.Account = label
+	}
+
+	switch algorithm := strings.ToUpper(query.Get("algorithm")); algorithm {
+	case "", "SHA1":
+	case "SHA256":
+		key.Algorithm = SHA256
+	case "SHA512":
+		key.Algorithm = SHA512
+	default:
+		return Key{}, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	if digits := query.Get("digits"); digits != "" {
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < MinDigits || n > MaxDigits {
+			return Key{}, fmt.Errorf("digits must be between %d and %d, got %q", MinDigits, MaxDigits, digits)
+		}
+		key.Digits = n
+	}
+	if period := query.Get("period"); period != "" {
+		n, err := strconv.Atoi(period)
+		if err != nil || n <= 0 || n > maxPeriod {
+			return Key{}, fmt.Errorf("period must be a positive number of seconds, got %q", period)
+		}
+		key.Period = time.Duration(n) * time.Second
+	}
+
+	return key, nil
+}
+
+// URI is the otpauth URI of the key, the portable form other apps read.
+func (k Key) URI() string {
+	query := url.Values{}
+	query.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret))
+	if k.Issuer != "" {
+		query.Set("issuer", k.Issuer)
+	}
+	query.Set("algorithm", k.Algorithm.String())
+	query.Set("digits", strconv.Itoa(k.Digits))
+	query.Set("period", strconv.Itoa(int(k.Period/time.Second)))
+
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + label, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// decodeSecret is lenient about case, spaces and padding since secrets are
+// often typed in by hand.
+func decodeSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return nil, ErrEmptySecret
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("secret is not valid base32: %w", err)
+	}
+	return secret, nil
+}
+
+// Code is the code valid at t.
+func (k Key) Code(t time.Time) string {
+	counter := uint64(t.Unix()) / uint64(k.Period/time.Second)
+	return hotp(k.Secret, counter, k.Algorithm, k.Digits)
+}
+
+// Remaining is how long the code at t stays valid.
+func (k Key) Remaining(t time.Time) time.Duration {
+	period := int64(k.Period / time.Second)
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
+
+// hotp is RFC 4226 with the hash swappable as RFC 6238 allows.
+func hotp(secret []byte, counter uint64, algorithm Algorithm, digits int) string {
+	mac := hmac.New(algorithm.hash(), secret)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for range digits {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulo)
+}
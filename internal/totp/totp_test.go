@@ -0,0 +1,137 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// seeds and codes from RFC 6238 appendix B
+var rfc6238Seeds = map[Algorithm]string{
+	SHA1:   "12345678901234567890",
+	SHA256: "12345678901234567890123456789012",
+	SHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+}
+
+var rfc6238Vectors = []struct {
+	unix      int64
+	algorithm Algorithm
+	code      string
+}{
+	{59, SHA1, "94287082"},
+	{59, SHA256, "46119246"},
+	{59, SHA512, "90693936"},
+	{1111111109, SHA1, "07081804"},
+	{1111111109, SHA256, "68084774"},
+	{1111111109, SHA512, "25091201"},
+	{1111111111, SHA1, "14050471"},
+	{1111111111, SHA256, "67062674"},
+	{1111111111, SHA512, "99943326"},
+	{1234567890, SHA1, "89005924"},
+	{1234567890, SHA256, "91819424"},
+	{1234567890, SHA512, "93441116"},
+	{2000000000, SHA1, "69279037"},
+	{2000000000, SHA256, "90698825"},
+	{2000000000, SHA512, "38618901"},
+	{20000000000, SHA1, "65353130"},
+	{20000000000, SHA256, "77737706"},
+	{20000000000, SHA512, "47863826"},
+}
+
+func TestRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		key := Key{
+			Secret:    []byte(rfc6238Seeds[v.algorithm]),
+			Algorithm: v.algorithm,
+			Digits:    8,
+			Period:    30 * time.Second,
+		}
+		if code := key.Code(time.Unix(v.unix, 0)); code != v.code {
+			t.Errorf("%v at %d: got %v, want %v", v.algorithm, v.unix, code, v.code)
+		}
+	}
+}
+
+func TestRFC6238URI(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		secret := base32.StdEncoding.EncodeToString([]byte(rfc6238Seeds[v.algorithm]))
+		uri := "otpauth://totp/Example:alice@example.com?secret=" + secret +
+			"&issuer=Example&digits=8&algorithm=" + v.algorithm.String()
+		key, err := Parse(uri)
+		if err != nil {
+			t.Fatalf("parse %v: %v", uri, err)
+		}
+		if code := key.Code(time.Unix(v.unix, 0)); code != v.code {
+			t.Errorf("%v at %d: got %v, want %v", v.algorithm, v.unix, code, v.code)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	key, err := Parse("otpauth://totp/ACME%20Co:john@example.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&algorithm=SHA512&digits=7&period=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Issuer != "ACME Co" || key.Account != "john@example.com" {
+		t.Errorf("label: got %q %q", key.Issuer, key.Account)
+	}
+	if key.Algorithm != SHA512 || key.Digits != 7 || key.Period != time.Minute {
+		t.Errorf("parameters: got %v %d %v", key.Algorithm, key.Digits, key.Period)
+	}
+
+	// bare secrets are forgiving about formatting and use the defaults
+	key, err = Parse("hxdm vjec jjws rb3h wizr 4ifu gftm xboz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Algorithm != SHA1 || key.Digits != DefaultDigits || key.Period != DefaultPeriod {
+		t.Errorf("defaults: got %v %d %v", key.Algorithm, key.Digits, key.Period)
+	}
+	if code := key.Code(time.Unix(0, 0)); len(code) != DefaultDigits {
+		t.Errorf("code %q is not %d digits", code, DefaultDigits)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"not base32!",
+		"otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP&counter=1",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=9",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=5",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&period=0",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/x",
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("parse %q: expected an error", invalid)
+		}
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	key := Key{Period: 30 * time.Second}
+	for unix, want := range map[int64]time.Duration{
+		0:  30 * time.Second,
+		1:  29 * time.Second,
+		29: time.Second,
+		30: 30 * time.Second,
+	} {
+		if got := key.Remaining(time.Unix(unix, 0)); got != want {
+			t.Errorf("remaining at %d: got %v, want %v", unix, got, want)
+		}
+	}
+}
+
+func TestURIRoundTrip(t *testing.T) {
+	key, err := Parse("otpauth://totp/ACME:alice@example.com?secret=JBSWY3DPEHPK3PXP&algorithm=SHA256&digits=8&period=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Parse(key.URI())
+	if err != nil {
+		t.Fatalf("parse %q: %v", key.URI(), err)
+	}
+	if again.Code(time.Unix(1234567890, 0)) != key.Code(time.Unix(1234567890, 0)) ||
+		again.Issuer != "ACME" || again.Account != "alice@example.com" || again.Period != time.Minute {
+		t.Errorf("round trip of %q gave %+v", key.URI(), again)
+	}
+}
@@ -0,0 +1,76 @@
+package trash
+
+import (
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+type KeyMap struct {
+	Quit    key.Binding
+	Nav     key.Binding
+	Restore key.Binding
+	Purge   key.Binding
+	Back    key.Binding
+}
+
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Quit, k.Nav, k.Restore, k.Purge, k.Back}
+}
+
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Quit, k.Nav, k.Back},
+		{k.Restore, k.Purge},
+	}
+}
+
+var keyMap = KeyMap{
+	Quit: key.NewBinding(
+		key.WithKeys("ctrl+c"),
+		key.WithHelp("ctrl+c", "quit"),
+	),
+	Nav: key.NewBinding(
+		key.WithKeys("up", "down", "k", "j"),
+		key.WithHelp("↑↓", "nav"),
+	),
+	Restore: key.NewBinding(
+		key.WithKeys("enter", "r"),
+		key.WithHelp("↵ r", "restore"),
+	),
+	Purge: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "purge"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "back"),
+	),
+}
+
+type Model struct {
+	keyMap    KeyMap
+	helpModel help.Model
+
+	// purging asks for x to be pressed a second time
+	purging bool
+
+	ids      []string
+	trashLoc int
+}
+
+func Initial() Model {
+	helpModel := help.New()
+	helpModel.Styles = uconst.HelpStyles
+	helpModel.ShowAll = true
+
+	return Model{
+		keyMap:    keyMap,
+		helpModel: helpModel,
+
+		purging: false,
+
+		ids: make([]string, 0),
+		// trashLoc
+	}
+}
@@ -0,0 +1,108 @@
+package trash
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dismint/dispass/internal/fuzzy"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/state"
+)
+
+// populateIDs lists the trash, most recently deleted first.
+func (m *Model) populateIDs(sm *state.Model) {
+	m.ids = m.ids[:0]
+	for id := range sm.Trash {
+		m.ids = append(m.ids, id)
+	}
+	sort.Slice(m.ids, func(i, j int) bool {
+		return sm.Trash[m.ids[i]].Deleted.After(sm.Trash[m.ids[j]].Deleted)
+	})
+	m.trashLoc = max(min(m.trashLoc, len(m.ids)-1), 0)
+}
+
+func (m *Model) transitionState(sm *state.Model) tea.Cmd {
+	sm.Screen = state.InteractScreen
+	m.purging = false
+	return func() tea.Msg { return state.CredsReloadedMsg{} }
+}
+
+func (m *Model) restore(sm *state.Model) tea.Cmd {
+	id := m.ids[m.trashLoc]
+	before := sm.Snapshot(id)
+	credInfo, _ := sm.RestoreCred(id)
+	fuzzy.UpdateFuzzy(sm, id, credInfo)
+	sm.Record(fmt.Sprintf("Restore %v", credInfo.Source), before)
+	passio.WriteStateCreds(sm)
+	m.populateIDs(sm)
+
+	return state.NotificationMsg(
+		fmt.Sprintf("Restored %v", credInfo.Source),
+		state.MessageLevelSuccess,
+	)
+}
+
+func (m *Model) purge(sm *state.Model) tea.Cmd {
+	id := m.ids[m.trashLoc]
+	source := sm.Trash[id].Source
+	before := sm.Snapshot(id)
+	delete(sm.Trash, id)
+	sm.Record(fmt.Sprintf("Purge %v", source), before)
+	passio.WriteStateCreds(sm)
+	m.purging = false
+	m.populateIDs(sm)
+
+	return state.NotificationMsg(
+		fmt.Sprintf("Purged %v", source),
+		state.MessageLevelSuccess,
+	)
+}
+
+func (m *Model) Update(msg tea.Msg, sm *state.Model) tea.Cmd {
+	cmds := make([]tea.Cmd, 0)
+
+	if sm.Dirty {
+		m.trashLoc = 0
+		m.purging = false
+		m.populateIDs(sm)
+		return nil
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, keyMap.Quit):
+			sm.Quitting = true
+			cmds = append(cmds, tea.Quit)
+		case key.Matches(msg, keyMap.Back):
+			if m.purging {
+				m.purging = false
+			} else {
+				cmds = append(cmds, m.transitionState(sm))
+			}
+		case len(m.ids) == 0:
+		case key.Matches(msg, keyMap.Purge):
+			if m.purging {
+				cmds = append(cmds, m.purge(sm))
+			} else {
+				m.purging = true
+			}
+		case m.purging:
+			// anything else calls off the purge
+			m.purging = false
+		case key.Matches(msg, keyMap.Restore):
+			cmds = append(cmds, m.restore(sm))
+		case key.Matches(msg, keyMap.Nav):
+			switch msg.String() {
+			case "up", "k":
+				m.trashLoc = max(m.trashLoc-1, 0)
+			case "down", "j":
+				m.trashLoc = min(m.trashLoc+1, len(m.ids)-1)
+			}
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
@@ -0,0 +1,58 @@
+package trash
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dismint/dispass/internal/state"
+	"github.com/dismint/dispass/internal/uconst"
+)
+
+const timeLayout = "2006-01-02 15:04"
+
+// retention reads better in days, which it usually is
+func retention() string {
+	const day = 24 * time.Hour
+	if uconst.TrashRetention%day == 0 {
+		days := int(uconst.TrashRetention / day)
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+	return uconst.TrashRetention.String()
+}
+
+func (m *Model) View(sm *state.Model) string {
+	var trashList string
+	for loc, id := range m.ids {
+		trashed := sm.Trash[id]
+		prefix := " "
+		if loc == m.trashLoc {
+			prefix = uconst.SymbolStyle.Render(">")
+		}
+		trashList += fmt.Sprintf("%v %v %v\n",
+			prefix,
+			uconst.TruncAndPadListElem(trashed.Source),
+			uconst.TextStyle.Render(trashed.Deleted.Local().Format(timeLayout)),
+		)
+	}
+	if trashList == "" {
+		trashList = "Trash is Empty\n"
+	}
+
+	view := fmt.Sprintf("%v\n\n%v",
+		m.helpModel.View(m.keyMap),
+		trashList,
+	)
+	if m.purging {
+		view += fmt.Sprintf("\n%v\n", uconst.MessageLevelErrorStyle.Render(
+			fmt.Sprintf("Purge %v for good? x to confirm", sm.Trash[m.ids[m.trashLoc]].Source),
+		))
+	} else if uconst.TrashRetention > 0 && len(m.ids) > 0 {
+		view += fmt.Sprintf("\n%v\n", uconst.TextStyle.Render(
+			fmt.Sprintf("Entries are purged %v after deletion", retention()),
+		))
+	}
+	return uconst.ViewStyle.Render(view)
+}
@@ -1,23 +1,29 @@
 package uconst
 
 import (
+	"errors"
+
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
 	"github.com/spf13/viper"
 )
 
-func LoadConfig() {
+func LoadConfig(overrides Dirs) {
+	resolveConfigDir(overrides)
+
 	viper.SetConfigName("dispass")
 
-	viper.AddConfigPath("$HOME/dispass")
+	viper.AddConfigPath(ConfigDir)
 	viper.AddConfigPath(".")
 
 	err := viper.ReadInConfig()
-	if err != nil {
+	if err != nil && !errors.As(err, &viper.ConfigFileNotFoundError{}) {
 		log.Errorf("fatal error reading config file: %v", err)
 	}
 
+	resolveDataDirs(overrides)
+
 	// colors
 	viper.SetDefault("colors.light.symbol", lostCentury13)
 	viper.SetDefault("colors.dark.symbol", lostCentury12)
@@ -37,6 +43,12 @@ func LoadConfig() {
 	viper.SetDefault("colors.dark.message_success", lostCentury12)
 	viper.SetDefault("colors.light.message_notif", lostCentury12)
 	viper.SetDefault("colors.dark.message_notif", lostCentury13)
+	viper.SetDefault("colors.light.strength_weak", lostCentury3)
+	viper.SetDefault("colors.dark.strength_weak", lostCentury2)
+	viper.SetDefault("colors.light.strength_fair", lostCentury7)
+	viper.SetDefault("colors.dark.strength_fair", lostCentury10)
+	viper.SetDefault("colors.light.strength_good", lostCentury13)
+	viper.SetDefault("colors.dark.strength_good", lostCentury12)
 
 	// set styles
 	SymbolStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
@@ -79,6 +91,17 @@ func LoadConfig() {
 		Dark:  viper.GetString("colors.dark.message_notif"),
 	})
 
+	// strength styles, weak covers the two lowest scores and good the two
+	// highest
+	strengthStyle := func(name string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
+			Light: viper.GetString("colors.light.strength_" + name),
+			Dark:  viper.GetString("colors.dark.strength_" + name),
+		})
+	}
+	weak, fair, good := strengthStyle("weak"), strengthStyle("fair"), strengthStyle("good")
+	StrengthStyles = [5]lipgloss.Style{weak, weak, fair, good, good}
+
 	HelpStyles = help.Styles{
 		ShortKey:       HelpKeyStyle,
 		ShortDesc:      HelpDescStyle,
@@ -87,4 +110,89 @@ func LoadConfig() {
 		FullDesc:       HelpDescStyle,
 		FullSeparator:  HelpSeparatorStyle,
 	}
+
+	// key derivation
+	viper.SetDefault("kdf.preset", DefaultKDFPreset)
+	preset, ok := KDFPresets[viper.GetString("kdf.preset")]
+	if !ok {
+		log.Errorf("unknown kdf preset %q, using %q",
+			viper.GetString("kdf.preset"), DefaultKDFPreset)
+		preset = KDFPresets[DefaultKDFPreset]
+	}
+	if viper.IsSet("kdf.time") {
+		preset.Time = viper.GetUint32("kdf.time")
+	}
+	if viper.IsSet("kdf.memory") {
+		preset.Memory = viper.GetUint32("kdf.memory")
+	}
+	if viper.IsSet("kdf.threads") {
+		preset.Threads = uint8(viper.GetUint("kdf.threads"))
+	}
+	KDF = preset
+
+	// backups
+	viper.SetDefault("backup.count", 5)
+	BackupCount = viper.GetInt("backup.count")
+
+	// security
+	viper.SetDefault("security.strict_permissions", false)
+	StrictPermissions = viper.GetBool("security.strict_permissions")
+
+	// password generator
+	viper.SetDefault("generator.mode", "characters")
+	viper.SetDefault("generator.length", 20)
+	viper.SetDefault("generator.lower", true)
+	viper.SetDefault("generator.upper", true)
+	viper.SetDefault("generator.digits", true)
+	viper.SetDefault("generator.symbols", true)
+	viper.SetDefault("generator.exclude_ambiguous", false)
+	viper.SetDefault("generator.min_per_class", 1)
+	viper.SetDefault("generator.words", 6)
+	viper.SetDefault("generator.separator", "-")
+	viper.SetDefault("generator.capitalize", false)
+	Generator = GeneratorSettings{
+		Passphrase:       viper.GetString("generator.mode") == "passphrase",
+		Length:           viper.GetInt("generator.length"),
+		Lower:            viper.GetBool("generator.lower"),
+		Upper:            viper.GetBool("generator.upper"),
+		Digits:           viper.GetBool("generator.digits"),
+		Symbols:          viper.GetBool("generator.symbols"),
+		ExcludeAmbiguous: viper.GetBool("generator.exclude_ambiguous"),
+		MinPerClass:      viper.GetInt("generator.min_per_class"),
+		Words:            viper.GetInt("generator.words"),
+		Separator:        viper.GetString("generator.separator"),
+		Capitalize:       viper.GetBool("generator.capitalize"),
+	}
+
+	// clipboard
+	viper.SetDefault("clipboard.clear_after", "30s")
+	ClipboardClearAfter = viper.GetDuration("clipboard.clear_after")
+
+	// locking
+	viper.SetDefault("lock.idle_after", "5m")
+	IdleLockAfter = viper.GetDuration("lock.idle_after")
+
+	// password history
+	viper.SetDefault("history.max_depth", 10)
+	HistoryDepth = max(viper.GetInt("history.max_depth"), 0)
+
+	// trash
+	viper.SetDefault("trash.retention", "720h")
+	TrashRetention = viper.GetDuration("trash.retention")
+
+	// pass
+	viper.SetDefault("pass.gpg", "gpg")
+	viper.SetDefault("pass.key", "")
+	GPGBinary = viper.GetString("pass.gpg")
+	GPGKey = viper.GetString("pass.key")
+
+	// audit
+	viper.SetDefault("audit.min_score", 3)
+	viper.SetDefault("audit.max_age", "8760h")
+	AuditMinScore = min(max(viper.GetInt("audit.min_score"), 0), 4)
+	AuditMaxAge = viper.GetDuration("audit.max_age")
+
+	// search
+	viper.SetDefault("search.order", "frecency")
+	SearchFrecency = viper.GetString("search.order") == "frecency"
 }
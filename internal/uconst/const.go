@@ -1,5 +0,0 @@
-package uconst
-
-const LogFileName = "dp.log"
-const DataFileName = "dp.dat"
-const BleveDirName = "index"
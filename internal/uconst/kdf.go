@@ -0,0 +1,18 @@
+package uconst
+
+// argon2id cost presets, loosely following the libsodium recommendations
+type KDFPreset struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+}
+
+var KDFPresets = map[string]KDFPreset{
+	"interactive": {Time: 3, Memory: 64 * 1024, Threads: 4},
+	"moderate":    {Time: 4, Memory: 256 * 1024, Threads: 4},
+	"sensitive":   {Time: 6, Memory: 1024 * 1024, Threads: 4},
+}
+
+const DefaultKDFPreset = "interactive"
+
+var KDF KDFPreset
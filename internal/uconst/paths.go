@@ -0,0 +1,102 @@
+package uconst
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/viper"
+)
+
+// older versions kept everything relative to the working directory, these are
+// only used to find files to migrate
+const (
+	LegacyDataFileName  = "dp.dat"
+	LegacyBackupDirName = "backups"
+	LegacyLogFileName   = "dp.log"
+	LegacyBleveDirName  = "index"
+)
+
+const (
+	appDirName    = "dispass"
+	dataFileName  = "dp.dat"
+	backupDirName = "backups"
+	logFileName   = "dp.log"
+)
+
+// Dirs overrides where dispass keeps its files, typically from command line
+// flags. Empty fields fall back to the environment, the config file and
+// finally the XDG base directories.
+type Dirs struct {
+	Data   string
+	Config string
+	State  string
+}
+
+var (
+	DataDir   string
+	ConfigDir string
+	StateDir  string
+
+	DataFilePath  string
+	BackupDirPath string
+	LogFilePath   string
+)
+
+// resolveDir picks the first of override, $envKey, the configKey setting and
+// $xdgEnv/dispass, falling back to ~/xdgDefault/dispass.
+func resolveDir(override, envKey, configKey, xdgEnv, xdgDefault string) string {
+	dir := override
+	if dir == "" {
+		dir = os.Getenv(envKey)
+	}
+	if dir == "" && configKey != "" {
+		dir = viper.GetString(configKey)
+	}
+	if dir == "" {
+		// relative XDG values are invalid per the spec and get ignored
+		if xdgDir := os.Getenv(xdgEnv); filepath.IsAbs(xdgDir) {
+			dir = filepath.Join(xdgDir, appDirName)
+		}
+	}
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			log.Fatalf("could not find home directory, set $%v: %v", envKey, err)
+		}
+		dir = filepath.Join(home, xdgDefault, appDirName)
+	}
+
+	dir = ExpandHome(dir)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return dir
+}
+
+// ExpandHome resolves a leading ~ to the home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+func resolveConfigDir(overrides Dirs) {
+	ConfigDir = resolveDir(overrides.Config, "DISPASS_CONFIG_DIR", "", "XDG_CONFIG_HOME", ".config")
+}
+
+// resolveDataDirs runs after the config file is read, so it can take part.
+func resolveDataDirs(overrides Dirs) {
+	DataDir = resolveDir(overrides.Data, "DISPASS_DATA_DIR", "paths.data_dir", "XDG_DATA_HOME", filepath.Join(".local", "share"))
+	StateDir = resolveDir(overrides.State, "DISPASS_STATE_DIR", "paths.state_dir", "XDG_STATE_HOME", filepath.Join(".local", "state"))
+
+	DataFilePath = filepath.Join(DataDir, dataFileName)
+	BackupDirPath = filepath.Join(DataDir, backupDirName)
+	LogFilePath = filepath.Join(StateDir, logFileName)
+}
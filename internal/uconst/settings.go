@@ -0,0 +1,56 @@
+package uconst
+
+import "time"
+
+// GeneratorSettings are the password generator defaults
+type GeneratorSettings struct {
+	Passphrase bool
+
+	Length           int
+	Lower            bool
+	Upper            bool
+	Digits           bool
+	Symbols          bool
+	ExcludeAmbiguous bool
+	MinPerClass      int
+
+	Words      int
+	Separator  string
+	Capitalize bool
+}
+
+var (
+	// BackupCount is how many previous versions of the vault are kept around
+	BackupCount int
+	// StrictPermissions refuses to start when sensitive files are readable by
+	// other users, instead of only warning
+	StrictPermissions bool
+	// Generator holds the password generator defaults
+	Generator GeneratorSettings
+	// ClipboardClearAfter is how long a copied secret stays on the clipboard,
+	// zero leaves it there
+	ClipboardClearAfter time.Duration
+	// IdleLockAfter is how long without a key press before the vault locks,
+	// zero never locks
+	IdleLockAfter time.Duration
+	// HistoryDepth is how many previous passwords each entry remembers, zero
+	// keeps none
+	HistoryDepth int
+	// TrashRetention is how long deleted entries stay in the trash, zero
+	// keeps them until purged by hand
+	TrashRetention time.Duration
+	// GPGBinary is the gpg used to read and write pass stores
+	GPGBinary string
+	// GPGKey is the key pass stores are exported for when they don't name
+	// their own, and tried first when decrypting
+	GPGKey string
+	// AuditMinScore is the strength score from 0 to 4 below which the audit
+	// reports a password as weak
+	AuditMinScore int
+	// AuditMaxAge is how long a password can go unchanged before the audit
+	// reports it as stale, zero never does
+	AuditMaxAge time.Duration
+	// SearchFrecency ranks search results by how often and recently entries
+	// were used as well as how well they match, until toggled in the main view
+	SearchFrecency bool
+)
@@ -1,7 +1,10 @@
 package uconst
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/reflow/truncate"
@@ -43,6 +46,8 @@ var (
 	MessageLevelNotifStyle   lipgloss.Style
 	BorderColor              lipgloss.AdaptiveColor
 	HelpStyles               help.Styles
+	// StrengthStyles are indexed by password strength score
+	StrengthStyles [5]lipgloss.Style
 )
 
 var (
@@ -61,6 +66,33 @@ func NewTextInput(prompt string) textinput.Model {
 	return ti
 }
 
+// NewTextArea is the multi line counterpart of NewTextInput, the prompt is
+// only shown on the first line so it lines up with the inputs around it.
+func NewTextArea(prompt string, height int) textarea.Model {
+	ta := textarea.New()
+	ta.ShowLineNumbers = false
+	promptWidth := lipgloss.Width(prompt)
+	ta.SetPromptFunc(promptWidth, func(lineIdx int) string {
+		if lineIdx == 0 {
+			return prompt
+		}
+		return strings.Repeat(" ", promptWidth)
+	})
+	focused, blurred := textarea.DefaultStyles()
+	for _, style := range []*textarea.Style{&focused, &blurred} {
+		style.Base = lipgloss.NewStyle()
+		style.CursorLine = TextStyle
+		style.Prompt = SymbolStyle
+		style.Text = TextStyle
+	}
+	ta.FocusedStyle = focused
+	ta.BlurredStyle = blurred
+	ta.Cursor.Style = SymbolStyle
+	ta.SetWidth(promptWidth + 32)
+	ta.SetHeight(height)
+	return ta
+}
+
 func TruncAndPadListElem(text string) string {
 	truncText := truncate.StringWithTail(text, 20, "…")
 	return TextStyle.Width(21).Render(truncText)
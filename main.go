@@ -1,20 +1,108 @@
 package main
 
 import (
+	"bufio"
+	"flag"
+	"fmt"
 	"os"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
+	"github.com/dismint/dispass/internal/cli"
+	"github.com/dismint/dispass/internal/clip"
 	"github.com/dismint/dispass/internal/master"
+	"github.com/dismint/dispass/internal/passio"
+	"github.com/dismint/dispass/internal/perm"
 	"github.com/dismint/dispass/internal/uconst"
+	"golang.org/x/term"
 )
 
+// findPermissionProblems checks the vault, backups and log, and the
+// directories holding them.
+func findPermissionProblems() []perm.Problem {
+	problems, err := perm.CheckDirs(uconst.DataDir, uconst.StateDir)
+	if err != nil {
+		log.Fatalf("could not check file permissions: %v", err)
+	}
+	files, err := perm.Check(uconst.DataFilePath, uconst.BackupDirPath, uconst.LogFilePath)
+	if err != nil {
+		log.Fatalf("could not check file permissions: %v", err)
+	}
+	return append(problems, files...)
+}
+
+// checkPermissions makes sure nobody else can read the vault or anything
+// derived from it, offering to fix what it can when attached to a terminal.
+func checkPermissions() {
+	problems := findPermissionProblems()
+
+	fixable := false
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", problem)
+		fixable = fixable || problem.Fixable()
+	}
+
+	if fixable && term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprint(os.Stderr, "restrict permissions now? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.EqualFold(strings.TrimSpace(answer), "y") {
+			if err := perm.Fix(problems); err != nil {
+				fmt.Fprintf(os.Stderr, "could not fix permissions: %v\n", err)
+				os.Exit(1)
+			}
+			// whatever is left can't be fixed by chmod
+			problems = findPermissionProblems()
+			for _, problem := range problems {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", problem)
+			}
+		}
+	}
+
+	if len(problems) > 0 && uconst.StrictPermissions {
+		fmt.Fprintln(os.Stderr, "refusing to start with insecure file permissions")
+		os.Exit(1)
+	}
+}
+
 func main() {
+	// the clipboard helper skips all setup, it only needs the clipboard
+	if len(os.Args) > 1 && os.Args[1] == clip.HelperCommand {
+		os.Exit(clip.RunHelper(os.Args[2:]))
+	}
+
+	var dirs uconst.Dirs
+	flag.StringVar(&dirs.Data, "data-dir", "", "directory holding the vault and its backups")
+	flag.StringVar(&dirs.Config, "config-dir", "", "directory holding dispass.toml")
+	flag.StringVar(&dirs.State, "state-dir", "", "directory holding the log")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: dispass [flags] [command]\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "without a command the interactive interface is started.\n\nflags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintln(flag.CommandLine.Output())
+		cli.Usage(flag.CommandLine.Output())
+	}
+	flag.Parse()
+
+	// load config file, this also settles where everything else lives
+	uconst.LoadConfig(dirs)
+
+	for _, dir := range []string{uconst.DataDir, uconst.StateDir} {
+		if err := os.MkdirAll(dir, perm.DirMode); err != nil {
+			log.Fatalf("could not create %v: %v", dir, err)
+		}
+	}
+	if err := passio.MigrateLegacyFiles(); err != nil {
+		log.Fatalf("could not migrate files from the working directory: %v", err)
+	}
+
+	checkPermissions()
+
 	// load logging file
 	logFd, err := os.OpenFile(
-		uconst.LogFileName,
+		uconst.LogFilePath,
 		os.O_CREATE|os.O_WRONLY|os.O_APPEND,
-		0644,
+		perm.FileMode,
 	)
 	if err != nil {
 		log.Fatalf("could not open log file: %v", err)
@@ -22,8 +110,9 @@ func main() {
 	defer logFd.Close()
 	log.SetOutput(logFd)
 
-	// load config file
-	uconst.LoadConfig()
+	if flag.NArg() > 0 {
+		os.Exit(cli.Run(flag.Args()))
+	}
 
 	if _, err := tea.NewProgram(master.Initial()).Run(); err != nil {
 		log.Fatalf("could not start program: %v", err)